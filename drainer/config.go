@@ -91,6 +91,13 @@ type TaskBinLogFilterRule struct {
 	IgnoreSQL *[]string `toml:"ignore-sql,omitempty" json:"ignore-sql,omitempty"`
 }
 
+// IgnoreColumnsRule defines the columns of a table to leave out of the row changes
+type IgnoreColumnsRule struct {
+	Schema  string   `toml:"db-name" json:"db-name"`
+	Table   string   `toml:"tbl-name" json:"tbl-name"`
+	Columns []string `toml:"columns" json:"columns"`
+}
+
 // SyncerConfig is the Syncer's configuration.
 type SyncerConfig struct {
 	StrSQLMode        *string            `toml:"sql-mode" json:"sql-mode"`
@@ -128,6 +135,20 @@ type SyncerConfig struct {
 	TableMigrateRule []TaskTableMigrateRule `toml:"table-migrate-rule" json:"table-migrate-rule"`
 
 	BinlogFilterRule map[string]TaskBinLogFilterRule `toml:"binlog-filter-rule,omitempty" json:"binlog-filter-rule,omitempty"`
+
+	// translation options for mysql and tidb
+	TimeZone             string              `toml:"time-zone" json:"time-zone"`
+	ZeroDatePolicy       string              `toml:"zero-date-policy" json:"zero-date-policy"`
+	ZeroDateMin          string              `toml:"zero-date-min" json:"zero-date-min"`
+	ZeroFillPadding      bool                `toml:"zero-fill-padding" json:"zero-fill-padding"`
+	ConvertCharsetPolicy string              `toml:"convert-charset-policy" json:"convert-charset-policy"`
+	ConvertCharset       string              `toml:"convert-charset" json:"convert-charset"`
+	DeadLetterFile       string              `toml:"dead-letter-file" json:"dead-letter-file"`
+	StripAutoIDCache     bool                `toml:"strip-auto-id-cache" json:"strip-auto-id-cache"`
+	EnumByName           bool                `toml:"enum-by-name" json:"enum-by-name"`
+	StrictColumnIDs      bool                `toml:"strict-column-ids" json:"strict-column-ids"`
+	StripStatsOptions    bool                `toml:"strip-stats-options" json:"strip-stats-options"`
+	IgnoreColumns        []IgnoreColumnsRule `toml:"ignore-columns" json:"ignore-columns"`
 }
 
 // EnableDispatch return true if enable dispatch.
@@ -421,6 +442,16 @@ func (cfg *Config) validateFilter() error {
 		}
 	}
 
+	for _, rule := range cfg.SyncerCfg.IgnoreColumns {
+		if len(rule.Schema) == 0 {
+			return errors.New("empty schema name in `ignore-columns` config")
+		}
+
+		if len(rule.Table) == 0 {
+			return errors.New("empty table name in `ignore-columns` config")
+		}
+	}
+
 	return nil
 }
 
@@ -119,6 +119,14 @@ func (t *testDrainerSuite) TestValidateFilter(c *C) {
 	cfg = NewConfig()
 	cfg.SyncerCfg.IgnoreTables = emptyTable
 	c.Assert(cfg.validateFilter(), NotNil)
+
+	cfg = NewConfig()
+	cfg.SyncerCfg.IgnoreColumns = []IgnoreColumnsRule{{Schema: "", Table: "t", Columns: []string{"a"}}}
+	c.Assert(cfg.validateFilter(), NotNil)
+
+	cfg = NewConfig()
+	cfg.SyncerCfg.IgnoreColumns = []IgnoreColumnsRule{{Schema: "s", Table: "", Columns: []string{"a"}}}
+	c.Assert(cfg.validateFilter(), NotNil)
 }
 
 func (t *testDrainerSuite) TestValidate(c *C) {
@@ -38,6 +38,8 @@ type MysqlSyncer struct {
 	db      *sql.DB
 	loader  loader.Loader
 	relayer relay.Relayer
+	// nil to keep the schema and table names
+	tableRouter *translator.TableRouter
 	*baseSyncer
 }
 
@@ -71,6 +73,33 @@ func CreateLoader(
 	opts = append(opts, loader.EnableDispatch(enableDispatch))
 	opts = append(opts, loader.EnableCausality(enableCausility))
 	opts = append(opts, loader.Merge(cfg.Merge))
+	opts = append(opts, loader.NullSafeEqual(cfg.NullSafeEqual))
+	opts = append(opts, loader.PrimaryKeyFirst(cfg.PKFirst))
+	opts = append(opts, loader.BeforeImageComment(cfg.BeforeImageComment))
+	opts = append(opts, loader.TxnSessionSQL(cfg.TxnSessionSQL))
+	opts = append(opts, loader.DetectReferencedTables(cfg.DetectReferencedTables))
+	opts = append(opts, loader.DDLMarkerTable(cfg.DDLMarkerSchema, cfg.DDLMarkerTable))
+	if cfg.MaxPlaceholders > 0 {
+		opts = append(opts, loader.MaxPlaceholders(cfg.MaxPlaceholders))
+	}
+	if cfg.LargeValueSize > 0 {
+		policy, err := parseLargeValuePolicy(cfg.LargeValuePolicy)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		opts = append(opts, loader.LargeValue(cfg.LargeValueSize, policy))
+	}
+	for _, hint := range cfg.IndexHints {
+		opts = append(opts, loader.UseIndex(hint.Schema, hint.Table, hint.Index))
+	}
+
+	if len(cfg.AllowDDLKinds) > 0 {
+		kinds := make([]loader.DDLKind, 0, len(cfg.AllowDDLKinds))
+		for _, kind := range cfg.AllowDDLKinds {
+			kinds = append(kinds, loader.DDLKind(kind))
+		}
+		opts = append(opts, loader.AllowDDLKinds(kinds...))
+	}
 
 	if cfg.SyncMode != 0 {
 		mode := loader.SyncMode(cfg.SyncMode)
@@ -85,6 +114,17 @@ func CreateLoader(
 	return
 }
 
+func parseLargeValuePolicy(policy string) (loader.LargeValuePolicy, error) {
+	switch policy {
+	case "", "single":
+		return loader.LargeValueSingle, nil
+	case "reject":
+		return loader.LargeValueReject, nil
+	default:
+		return 0, errors.Errorf("unknown large-value-policy: %s", policy)
+	}
+}
+
 // NewMysqlSyncer returns a instance of MysqlSyncer
 func NewMysqlSyncer(
 	cfg *DBConfig,
@@ -98,6 +138,7 @@ func NewMysqlSyncer(
 	info *loopbacksync.LoopBackSync,
 	enableDispatch bool,
 	enableCausility bool,
+	tableRouter *translator.TableRouter,
 ) (*MysqlSyncer, error) {
 	if cfg.TLS != nil {
 		log.Info("enable TLS to connect downstream MySQL/TiDB")
@@ -132,10 +173,11 @@ func NewMysqlSyncer(
 	}
 
 	s := &MysqlSyncer{
-		db:         db,
-		loader:     loader,
-		relayer:    relayer,
-		baseSyncer: newBaseSyncer(tableInfoGetter),
+		db:          db,
+		loader:      loader,
+		relayer:     relayer,
+		tableRouter: tableRouter,
+		baseSyncer:  newBaseSyncer(tableInfoGetter),
 	}
 
 	go s.run()
@@ -183,7 +225,7 @@ func (m *MysqlSyncer) Sync(item *Item) error {
 		item.RelayLogPos = pos
 	}
 
-	txn, err := translator.TiBinlogToTxn(m.tableInfoGetter, item.Schema, item.Table, item.Binlog, item.PrewriteValue, item.ShouldSkip)
+	txn, err := translator.TiBinlogToTxn(m.tableInfoGetter, item.Schema, item.Table, item.Binlog, item.PrewriteValue, item.ShouldSkip, m.tableRouter)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -194,3 +194,16 @@ func (s *mysqlSuite) TestRelaxSQLMode(c *check.C) {
 		c.Assert(getNew, check.Equals, test.newMode)
 	}
 }
+
+func (s *mysqlSuite) TestParseLargeValuePolicy(c *check.C) {
+	policy, err := parseLargeValuePolicy("")
+	c.Assert(err, check.IsNil)
+	c.Assert(policy, check.Equals, loader.LargeValueSingle)
+
+	policy, err = parseLargeValuePolicy("reject")
+	c.Assert(err, check.IsNil)
+	c.Assert(policy, check.Equals, loader.LargeValueReject)
+
+	_, err = parseLargeValuePolicy("drop")
+	c.Assert(err, check.ErrorMatches, ".*unknown large-value-policy: drop.*")
+}
@@ -45,6 +45,30 @@ type DBConfig struct {
 
 	Merge bool `toml:"merge" json:"merge"`
 
+	// kinds of DDL skipped by default but should be executed, like "privilege"
+	AllowDDLKinds []string `toml:"allow-ddl-kinds" json:"allow-ddl-kinds"`
+	// match the key columns by `<=>` in the WHERE of update and delete
+	NullSafeEqual bool `toml:"null-safe-equal" json:"null-safe-equal"`
+	// indexes to hint by USE INDEX in the update of the tables
+	IndexHints []IndexHint `toml:"index-hints" json:"index-hints"`
+	// record every executed DDL into the table if both are set
+	DDLMarkerSchema string `toml:"ddl-marker-schema" json:"ddl-marker-schema"`
+	DDLMarkerTable  string `toml:"ddl-marker-table" json:"ddl-marker-table"`
+	// put the primary key columns first in the column list of insert and replace
+	PKFirst bool `toml:"pk-first" json:"pk-first"`
+	// append a comment recording the before-image key values to update
+	BeforeImageComment bool `toml:"before-image-comment" json:"before-image-comment"`
+	// the statement to execute at the beginning of every transaction
+	TxnSessionSQL string `toml:"txn-session-sql" json:"txn-session-sql"`
+	// the size threshold in bytes of any single TEXT/BLOB value, 0 means no limit
+	LargeValueSize int `toml:"large-value-size" json:"large-value-size"`
+	// how to handle the DML having a value larger than LargeValueSize, "single" or "reject"
+	LargeValuePolicy string `toml:"large-value-policy" json:"large-value-policy"`
+	// the max number of placeholders in a bulk replace, 0 means the loader default
+	MaxPlaceholders int `toml:"max-placeholders" json:"max-placeholders"`
+	// look up the tables referenced by foreign keys, their updates are never split into delete and insert
+	DetectReferencedTables bool `toml:"detect-referenced-tables" json:"detect-referenced-tables"`
+
 	ZKAddrs             string `toml:"zookeeper-addrs" json:"zookeeper-addrs"`
 	KafkaAddrs          string `toml:"kafka-addrs" json:"kafka-addrs"`
 	KafkaVersion        string `toml:"kafka-version" json:"kafka-version"`
@@ -57,6 +81,13 @@ type DBConfig struct {
 	ClusterID uint64 `toml:"-" json:"-"`
 }
 
+// IndexHint is the index to hint for the update of a downstream table.
+type IndexHint struct {
+	Schema string `toml:"db-name" json:"db-name"`
+	Table  string `toml:"tbl-name" json:"tbl-name"`
+	Index  string `toml:"index" json:"index"`
+}
+
 // CheckpointConfig is the Checkpoint configuration.
 type CheckpointConfig struct {
 	Type     string `toml:"type" json:"type"`
@@ -0,0 +1,276 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package translator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/format"
+)
+
+// ConvertCharsetPolicy decides how to translate `ALTER TABLE ... CONVERT TO CHARACTER SET`,
+// which rewrites the charset of all the string columns.
+type ConvertCharsetPolicy int
+
+// ConvertCharsetPolicy values.
+const (
+	// ConvertCharsetPass keeps the statement as it is.
+	ConvertCharsetPass ConvertCharsetPolicy = iota
+	// ConvertCharsetStrip removes the conversion from the statement,
+	// the statement is skipped if nothing else is left.
+	ConvertCharsetStrip
+	// ConvertCharsetRewrite converts to the configured charset instead,
+	// with the default collation of it.
+	ConvertCharsetRewrite
+)
+
+var (
+	convertCharsetPolicy = ConvertCharsetPass
+	convertCharsetTarget string
+)
+
+// SetConvertCharsetPolicy set how to translate `ALTER TABLE ... CONVERT TO CHARACTER SET`,
+// charset is the one to convert to for ConvertCharsetRewrite.
+func SetConvertCharsetPolicy(policy ConvertCharsetPolicy, charset string) error {
+	if policy == ConvertCharsetRewrite && len(charset) == 0 {
+		return errors.New("charset to convert to is empty")
+	}
+
+	convertCharsetPolicy = policy
+	convertCharsetTarget = charset
+	return nil
+}
+
+var (
+	stripAutoIDCache  bool
+	stripStatsOptions bool
+)
+
+// SetStripAutoIDCache set whether to remove the TiDB specific AUTO_ID_CACHE option
+// from CREATE TABLE and ALTER TABLE, the ALTER TABLE is skipped if nothing else is left.
+func SetStripAutoIDCache(strip bool) {
+	stripAutoIDCache = strip
+}
+
+// SetStripStatsOptions set whether to remove STATS_PERSISTENT, STATS_AUTO_RECALC and
+// STATS_SAMPLE_PAGES from CREATE TABLE and ALTER TABLE. TiDB ignores them, so they
+// don't stand for the statistics of the upstream table, and STATS_PERSISTENT is
+// restored as DEFAULT with a comment. The ALTER TABLE is skipped if nothing else is left.
+func SetStripStatsOptions(strip bool) {
+	stripStatsOptions = strip
+}
+
+// ErrDDLNotInvertible means the DDL can't be reverted by another DDL,
+// like DROP TABLE or any statement losing data.
+var ErrDDLNotInvertible = errors.New("ddl is not invertible")
+
+// GenInverseDDL returns the DDL reverting the given one, schema is used
+// when the statement doesn't specify the schema of the table.
+// Only statements that create something can be inverted:
+// CREATE DATABASE, CREATE TABLE, CREATE INDEX and ALTER TABLE adding columns or indexes.
+// The ones with IF NOT EXISTS are not, the object may exist before and be dropped by the inverse.
+func GenInverseDDL(sql string, schema string) (string, error) {
+	stmt, err := getParser().ParseOneStmt(sql, "", "")
+	if err != nil {
+		return "", errors.Annotatef(err, "parse ddl: %s", sql)
+	}
+
+	switch node := stmt.(type) {
+	case *ast.CreateDatabaseStmt:
+		if node.IfNotExists {
+			break
+		}
+		return fmt.Sprintf("DROP DATABASE %s", quoteName(node.Name)), nil
+	case *ast.CreateTableStmt:
+		if node.IfNotExists {
+			break
+		}
+		return fmt.Sprintf("DROP TABLE %s", quoteTableName(node.Table, schema)), nil
+	case *ast.CreateIndexStmt:
+		if node.IfNotExists {
+			break
+		}
+		return fmt.Sprintf("DROP INDEX %s ON %s", quoteName(node.IndexName), quoteTableName(node.Table, schema)), nil
+	case *ast.AlterTableStmt:
+		specs, err := inverseAlterTableSpecs(node.Specs)
+		if err != nil {
+			return "", errors.Annotatef(err, "ddl: %s", sql)
+		}
+		return fmt.Sprintf("ALTER TABLE %s %s", quoteTableName(node.Table, schema), strings.Join(specs, ", ")), nil
+	}
+
+	return "", errors.Annotatef(ErrDDLNotInvertible, "ddl: %s", sql)
+}
+
+func inverseAlterTableSpecs(specs []*ast.AlterTableSpec) ([]string, error) {
+	var inverse []string
+	// revert in the reverse order of the original specs
+	for i := len(specs) - 1; i >= 0; i-- {
+		spec := specs[i]
+		if spec.IfNotExists || (spec.Constraint != nil && spec.Constraint.IfNotExists) {
+			return nil, errors.Annotate(ErrDDLNotInvertible, "if not exists")
+		}
+		switch spec.Tp {
+		case ast.AlterTableAddColumns:
+			for j := len(spec.NewColumns) - 1; j >= 0; j-- {
+				inverse = append(inverse, "DROP COLUMN "+quoteName(spec.NewColumns[j].Name.Name.O))
+			}
+		case ast.AlterTableAddConstraint:
+			switch spec.Constraint.Tp {
+			case ast.ConstraintPrimaryKey:
+				inverse = append(inverse, "DROP PRIMARY KEY")
+			case ast.ConstraintIndex, ast.ConstraintKey, ast.ConstraintUniq,
+				ast.ConstraintUniqKey, ast.ConstraintUniqIndex:
+				if len(spec.Constraint.Name) == 0 {
+					return nil, errors.Annotate(ErrDDLNotInvertible, "index without name")
+				}
+				inverse = append(inverse, "DROP INDEX "+quoteName(spec.Constraint.Name))
+			default:
+				return nil, errors.Annotatef(ErrDDLNotInvertible, "constraint type: %v", spec.Constraint.Tp)
+			}
+		default:
+			return nil, errors.Annotatef(ErrDDLNotInvertible, "alter table type: %v", spec.Tp)
+		}
+	}
+
+	return inverse, nil
+}
+
+func quoteTableName(table *ast.TableName, defaultSchema string) string {
+	schema := table.Schema.O
+	if len(schema) == 0 {
+		schema = defaultSchema
+	}
+
+	if len(schema) == 0 {
+		return quoteName(table.Name.O)
+	}
+
+	return quoteName(schema) + "." + quoteName(table.Name.O)
+}
+
+// translateDDL applies the configured policies to the DDL,
+// skip is true if the DDL shouldn't be executed downstream.
+func translateDDL(sql string) (newSQL string, skip bool, err error) {
+	if convertCharsetPolicy == ConvertCharsetPass && !stripAutoIDCache && !stripStatsOptions {
+		return sql, false, nil
+	}
+
+	stmt, err := getParser().ParseOneStmt(sql, "", "")
+	if err != nil {
+		return "", false, errors.Annotatef(err, "parse ddl: %s", sql)
+	}
+
+	changed := false
+	switch node := stmt.(type) {
+	case *ast.CreateTableStmt:
+		node.Options, changed = stripTableOptions(node.Options)
+	case *ast.AlterTableStmt:
+		node.Specs, changed = translateAlterTableSpecs(node.Specs)
+		if changed && len(node.Specs) == 0 {
+			return sql, true, nil
+		}
+	}
+
+	if !changed {
+		return sql, false, nil
+	}
+
+	var builder strings.Builder
+	if err = stmt.Restore(format.NewRestoreCtx(restoreFlags, &builder)); err != nil {
+		return "", false, errors.Annotatef(err, "restore ddl: %s", sql)
+	}
+
+	return builder.String(), false, nil
+}
+
+func translateAlterTableSpecs(origin []*ast.AlterTableSpec) (specs []*ast.AlterTableSpec, changed bool) {
+	specs = make([]*ast.AlterTableSpec, 0, len(origin))
+	for _, spec := range origin {
+		if spec.Tp != ast.AlterTableOption {
+			specs = append(specs, spec)
+			continue
+		}
+
+		if isConvertCharset(spec) && convertCharsetPolicy != ConvertCharsetPass {
+			changed = true
+			if convertCharsetPolicy == ConvertCharsetRewrite {
+				// drop the collation since it may not belong to the new charset
+				spec.Options = []*ast.TableOption{{
+					Tp:        ast.TableOptionCharset,
+					StrValue:  convertCharsetTarget,
+					UintValue: ast.TableOptionCharsetWithConvertTo,
+				}}
+				specs = append(specs, spec)
+			}
+			continue
+		}
+
+		var stripped bool
+		spec.Options, stripped = stripTableOptions(spec.Options)
+		if stripped {
+			changed = true
+			if len(spec.Options) == 0 {
+				continue
+			}
+		}
+		specs = append(specs, spec)
+	}
+
+	return specs, changed
+}
+
+// stripTableOptions removes the options not to be executed downstream.
+func stripTableOptions(options []*ast.TableOption) ([]*ast.TableOption, bool) {
+	if !stripAutoIDCache && !stripStatsOptions {
+		return options, false
+	}
+
+	kept := make([]*ast.TableOption, 0, len(options))
+	for _, opt := range options {
+		if !isStrippedTableOption(opt.Tp) {
+			kept = append(kept, opt)
+		}
+	}
+
+	return kept, len(kept) != len(options)
+}
+
+func isStrippedTableOption(tp ast.TableOptionType) bool {
+	switch tp {
+	case ast.TableOptionAutoIdCache:
+		return stripAutoIDCache
+	case ast.TableOptionStatsPersistent, ast.TableOptionStatsAutoRecalc, ast.TableOptionStatsSamplePages:
+		return stripStatsOptions
+	}
+
+	return false
+}
+
+func isConvertCharset(spec *ast.AlterTableSpec) bool {
+	if spec.Tp != ast.AlterTableOption {
+		return false
+	}
+
+	for _, opt := range spec.Options {
+		if opt.Tp == ast.TableOptionCharset && opt.UintValue == ast.TableOptionCharsetWithConvertTo {
+			return true
+		}
+	}
+
+	return false
+}
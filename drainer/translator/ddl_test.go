@@ -0,0 +1,218 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package translator
+
+import (
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+)
+
+type testDDLSuite struct{}
+
+var _ = check.Suite(&testDDLSuite{})
+
+func (s *testDDLSuite) TestGenInverseDDL(c *check.C) {
+	tests := []struct {
+		sql     string
+		inverse string
+	}{
+		{"create database db1", "DROP DATABASE `db1`"},
+		{"create table t1(id int primary key)", "DROP TABLE `test`.`t1`"},
+		{"create table db1.t1(id int primary key)", "DROP TABLE `db1`.`t1`"},
+		{"create index idx on t1(a)", "DROP INDEX `idx` ON `test`.`t1`"},
+		{"alter table t1 add column a int", "ALTER TABLE `test`.`t1` DROP COLUMN `a`"},
+		{"alter table t1 add column (a int, b int)", "ALTER TABLE `test`.`t1` DROP COLUMN `b`, DROP COLUMN `a`"},
+		{"alter table t1 add column a int, add unique key uk(a)", "ALTER TABLE `test`.`t1` DROP INDEX `uk`, DROP COLUMN `a`"},
+		{"alter table t1 add primary key(id)", "ALTER TABLE `test`.`t1` DROP PRIMARY KEY"},
+	}
+
+	for _, test := range tests {
+		inverse, err := GenInverseDDL(test.sql, "test")
+		c.Assert(err, check.IsNil, check.Commentf("sql: %s", test.sql))
+		c.Assert(inverse, check.Equals, test.inverse, check.Commentf("sql: %s", test.sql))
+	}
+}
+
+func (s *testDDLSuite) TestGenInverseDDLNotInvertible(c *check.C) {
+	sqls := []string{
+		"drop table t1",
+		"drop database db1",
+		"truncate table t1",
+		"alter table t1 drop column a",
+		"alter table t1 modify column a varchar(10)",
+		"alter table t1 add column a int, drop column b",
+		"alter table t1 add index(a)",
+		// the objects may exist before
+		"create database if not exists db1",
+		"create table if not exists t1(id int primary key)",
+		"create index if not exists idx on t1(a)",
+		"alter table t1 add column if not exists a int",
+		"alter table t1 add index if not exists idx(a)",
+	}
+
+	for _, sql := range sqls {
+		_, err := GenInverseDDL(sql, "test")
+		c.Assert(errors.Cause(err), check.Equals, ErrDDLNotInvertible, check.Commentf("sql: %s", sql))
+	}
+}
+
+func (s *testDDLSuite) TestTranslateConvertCharset(c *check.C) {
+	defer func() {
+		err := SetConvertCharsetPolicy(ConvertCharsetPass, "")
+		c.Assert(err, check.IsNil)
+	}()
+
+	convert := "alter table t1 convert to character set latin1 collate latin1_bin"
+	mixed := "alter table t1 add column a int, convert to character set latin1"
+	tests := []struct {
+		policy ConvertCharsetPolicy
+		sql    string
+		expect string
+		skip   bool
+	}{
+		{ConvertCharsetPass, convert, convert, false},
+		{ConvertCharsetPass, mixed, mixed, false},
+		{ConvertCharsetStrip, convert, convert, true},
+		{ConvertCharsetStrip, mixed, "ALTER TABLE `t1` ADD COLUMN `a` INT", false},
+		{ConvertCharsetRewrite, convert, "ALTER TABLE `t1` CONVERT TO CHARACTER SET UTF8MB4", false},
+		{ConvertCharsetRewrite, mixed, "ALTER TABLE `t1` ADD COLUMN `a` INT, CONVERT TO CHARACTER SET UTF8MB4", false},
+		// TiDB specific clauses are kept in the special comments
+		{ConvertCharsetStrip, "alter table t1 /*T! SHARD_ROW_ID_BITS=4 */, convert to character set latin1", "ALTER TABLE `t1` /*T! SHARD_ROW_ID_BITS = 4 */", false},
+		{ConvertCharsetRewrite, "alter table t1 convert to character set latin1, /*T! SHARD_ROW_ID_BITS=4 */", "ALTER TABLE `t1` CONVERT TO CHARACTER SET UTF8MB4, /*T! SHARD_ROW_ID_BITS = 4 */", false},
+		// other statements are never changed
+		{ConvertCharsetStrip, "alter table t1 charset = latin1", "alter table t1 charset = latin1", false},
+		{ConvertCharsetRewrite, "create table t1(a int)", "create table t1(a int)", false},
+	}
+
+	for _, test := range tests {
+		err := SetConvertCharsetPolicy(test.policy, "utf8mb4")
+		c.Assert(err, check.IsNil)
+
+		sql, skip, err := translateDDL(test.sql)
+		c.Assert(err, check.IsNil)
+		c.Assert(sql, check.Equals, test.expect, check.Commentf("policy: %d, sql: %s", test.policy, test.sql))
+		c.Assert(skip, check.Equals, test.skip, check.Commentf("policy: %d, sql: %s", test.policy, test.sql))
+	}
+
+	err := SetConvertCharsetPolicy(ConvertCharsetRewrite, "")
+	c.Assert(err, check.NotNil)
+}
+
+func (s *testDDLSuite) TestTranslateStripAutoIDCache(c *check.C) {
+	defer SetStripAutoIDCache(false)
+
+	create := "create table t1(id int primary key) auto_id_cache 100"
+	tests := []struct {
+		strip  bool
+		sql    string
+		expect string
+		skip   bool
+	}{
+		{false, create, create, false},
+		{true, create, "CREATE TABLE `t1` (`id` INT PRIMARY KEY)", false},
+		{true, "create table t1(id int primary key) auto_id_cache = 1 comment 'c'", "CREATE TABLE `t1` (`id` INT PRIMARY KEY) COMMENT = 'c'", false},
+		{true, "alter table t1 auto_id_cache 100", "alter table t1 auto_id_cache 100", true},
+		{true, "alter table t1 auto_id_cache 100, comment 'c'", "ALTER TABLE `t1` COMMENT = 'c'", false},
+		{true, "create table t1(id int primary key)", "create table t1(id int primary key)", false},
+	}
+
+	for _, test := range tests {
+		SetStripAutoIDCache(test.strip)
+
+		sql, skip, err := translateDDL(test.sql)
+		c.Assert(err, check.IsNil)
+		c.Assert(sql, check.Equals, test.expect, check.Commentf("strip: %v, sql: %s", test.strip, test.sql))
+		c.Assert(skip, check.Equals, test.skip, check.Commentf("strip: %v, sql: %s", test.strip, test.sql))
+	}
+}
+
+func (s *testDDLSuite) TestTranslateStripAutoIDCacheKeepTiDBComments(c *check.C) {
+	defer SetStripAutoIDCache(false)
+	SetStripAutoIDCache(true)
+
+	tests := []struct {
+		sql    string
+		expect string
+	}{
+		{
+			"create table t1(id bigint primary key /*T![auto_rand] AUTO_RANDOM(5) */) /*T! SHARD_ROW_ID_BITS=4 */ auto_id_cache 100",
+			"CREATE TABLE `t1` (`id` BIGINT PRIMARY KEY /*T![auto_rand] AUTO_RANDOM(5) */) /*T! SHARD_ROW_ID_BITS = 4 */",
+		},
+		{
+			"alter table t1 auto_id_cache 100, /*T! SHARD_ROW_ID_BITS=4 */",
+			"ALTER TABLE `t1` /*T! SHARD_ROW_ID_BITS = 4 */",
+		},
+	}
+
+	for _, test := range tests {
+		sql, skip, err := translateDDL(test.sql)
+		c.Assert(err, check.IsNil)
+		c.Assert(sql, check.Equals, test.expect, check.Commentf("sql: %s", test.sql))
+		c.Assert(skip, check.IsFalse)
+	}
+}
+
+func (s *testDDLSuite) TestTranslateStripStatsOptions(c *check.C) {
+	defer SetStripStatsOptions(false)
+
+	alter := "alter table t1 stats_persistent = 1, stats_auto_recalc = 0, stats_sample_pages = 10"
+	tests := []struct {
+		strip  bool
+		sql    string
+		expect string
+		skip   bool
+	}{
+		{false, alter, alter, false},
+		{true, alter, alter, true},
+		{true, "alter table t1 stats_auto_recalc = 1, comment 'c'", "ALTER TABLE `t1` COMMENT = 'c'", false},
+		{true, "alter table t1 add column a int, stats_sample_pages = default", "ALTER TABLE `t1` ADD COLUMN `a` INT", false},
+		{true, "create table t1(id int primary key) stats_persistent = 0", "CREATE TABLE `t1` (`id` INT PRIMARY KEY)", false},
+		{true, "alter table t1 comment 'c'", "alter table t1 comment 'c'", false},
+	}
+
+	for _, test := range tests {
+		SetStripStatsOptions(test.strip)
+
+		sql, skip, err := translateDDL(test.sql)
+		c.Assert(err, check.IsNil)
+		c.Assert(sql, check.Equals, test.expect, check.Commentf("strip: %v, sql: %s", test.strip, test.sql))
+		c.Assert(skip, check.Equals, test.skip, check.Commentf("strip: %v, sql: %s", test.strip, test.sql))
+	}
+}
+
+func (s *testDDLSuite) TestTranslateStripStatsOptionsKeepTiDBComments(c *check.C) {
+	defer SetStripStatsOptions(false)
+	SetStripStatsOptions(true)
+
+	tests := []struct {
+		sql    string
+		expect string
+	}{
+		{
+			"create table t1(id bigint primary key /*T![auto_rand] AUTO_RANDOM(5) */) stats_persistent = 0",
+			"CREATE TABLE `t1` (`id` BIGINT PRIMARY KEY /*T![auto_rand] AUTO_RANDOM(5) */)",
+		},
+		{
+			"alter table t1 stats_auto_recalc = 1, /*T! SHARD_ROW_ID_BITS=4 */",
+			"ALTER TABLE `t1` /*T! SHARD_ROW_ID_BITS = 4 */",
+		},
+	}
+
+	for _, test := range tests {
+		sql, skip, err := translateDDL(test.sql)
+		c.Assert(err, check.IsNil)
+		c.Assert(sql, check.Equals, test.expect, check.Commentf("sql: %s", test.sql))
+		c.Assert(skip, check.IsFalse)
+	}
+}
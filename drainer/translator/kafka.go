@@ -147,7 +147,7 @@ func genTable(schema string, tableInfo *model.TableInfo) (table *obinlog.Table)
 }
 
 func insertRowToRow(ptableInfo, tableInfo *model.TableInfo, raw []byte) (row *obinlog.Row, err error) {
-	columnValues, err := insertRowToDatums(tableInfo, raw)
+	columnValues, err := insertRowToDatums(ptableInfo, tableInfo, raw)
 	columns := tableInfo.Columns
 
 	row = new(obinlog.Row)
@@ -169,6 +169,9 @@ func deleteRowToRow(ptableinfo, tableInfo *model.TableInfo, raw []byte) (row *ob
 	columns := tableInfo.Columns
 
 	colsTypeMap := util.ToColumnTypeMap(tableInfo.Columns)
+	if err = checkColumnIDs(ptableinfo, tableInfo, raw); err != nil {
+		return nil, errors.Trace(err)
+	}
 	columnValues, err := tablecodec.DecodeRowToDatumMap(raw, colsTypeMap, time.Local)
 	if err != nil {
 		return nil, errors.Annotate(err, "DecodeRow failed")
@@ -192,6 +195,9 @@ func deleteRowToRow(ptableinfo, tableInfo *model.TableInfo, raw []byte) (row *ob
 }
 
 func updateRowToRow(ptableinfo, tableInfo *model.TableInfo, raw []byte, canAppendDefaultValue bool) (row *obinlog.Row, changedRow *obinlog.Row, err error) {
+	if err = checkColumnIDs(ptableinfo, tableInfo, raw); err != nil {
+		return nil, nil, errors.Trace(err)
+	}
 	updtDecoder := newUpdateDecoder(ptableinfo, tableInfo, canAppendDefaultValue)
 	oldDatums, newDatums, err := updtDecoder.decode(raw, time.Local)
 	if err != nil {
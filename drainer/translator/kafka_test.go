@@ -145,6 +145,25 @@ func checkColumn(c *check.C, info *obinlog.ColumnInfo, col *obinlog.Column, datu
 	c.Assert(colV, check.Equals, datumV)
 }
 
+func (t *testKafkaSuite) TestDecimalAsString(c *check.C) {
+	colInfo := &model.ColumnInfo{
+		FieldType: types.FieldType{
+			Tp:      mysql.TypeNewDecimal,
+			Flen:    20,
+			Decimal: 4,
+		},
+	}
+
+	dec := new(types.MyDecimal)
+	err := dec.FromString([]byte("1234567890123456.7890"))
+	c.Assert(err, check.IsNil)
+
+	col := DatumToColumn(colInfo, types.NewDecimalDatum(dec))
+	c.Assert(col.StringValue, check.NotNil)
+	c.Assert(col.GetStringValue(), check.Equals, "1234567890123456.7890")
+	c.Assert(col.DoubleValue, check.IsNil)
+}
+
 func (t *testKafkaSuite) TestGenTable(c *check.C) {
 	schema := "test"
 	table := "test"
@@ -16,6 +16,9 @@ package translator
 import (
 	"fmt"
 	"io"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pingcap/errors"
@@ -24,17 +27,140 @@ import (
 	"github.com/pingcap/tidb-binlog/pkg/util"
 	"github.com/pingcap/tidb/parser/model"
 	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tidb/sessionctx/stmtctx"
 	"github.com/pingcap/tidb/tablecodec"
 	"github.com/pingcap/tidb/types"
 	tipb "github.com/pingcap/tipb/go-binlog"
+	"go.uber.org/zap"
 )
 
 const implicitColID = -1
 
+// ZeroDatePolicy decides how to translate zero or partial dates like
+// `0000-00-00` and `2020-00-15`, which are rejected by a downstream running with
+// `NO_ZERO_DATE` or `NO_ZERO_IN_DATE`.
+type ZeroDatePolicy int
+
+// ZeroDatePolicy values.
+const (
+	// ZeroDateKeep keeps the value as it is.
+	ZeroDateKeep ZeroDatePolicy = iota
+	// ZeroDateToNull translates the value into NULL if the column is nullable,
+	// or into the minimum date otherwise.
+	ZeroDateToNull
+	// ZeroDateToMin translates the value into the minimum date.
+	ZeroDateToMin
+)
+
+var (
+	zeroDatePolicy = ZeroDateKeep
+	zeroDateMin    types.Time
+
+	zeroFillPadding = false
+
+	enumByName = false
+
+	deadLetter DeadLetterFunc
+
+	// nil to keep the TIMESTAMP values in the local time zone
+	timeZone *time.Location
+
+	// lower case `schema.table` -> lower case column names
+	ignoreColumns map[string]map[string]struct{}
+)
+
+// SetTimeZone set the time zone of the downstream session, TIMESTAMP values are translated
+// into it so they're stored as the same instant. DATETIME and DATE values have no time zone
+// and are never translated. Pass nil to use the local time zone of drainer, which is the default.
+func SetTimeZone(loc *time.Location) {
+	timeZone = loc
+}
+
+// SetIgnoreColumns set the columns to leave out of the row changes, keyed by `schema.table`.
+// Both the table and column names are case-insensitive. The primary key columns are always
+// kept since the downstream needs them to locate the rows.
+func SetIgnoreColumns(columns map[string][]string) {
+	ignoreColumns = make(map[string]map[string]struct{}, len(columns))
+	for table, names := range columns {
+		table = strings.ToLower(table)
+		if ignoreColumns[table] == nil {
+			ignoreColumns[table] = make(map[string]struct{}, len(names))
+		}
+		for _, name := range names {
+			ignoreColumns[table][strings.ToLower(name)] = struct{}{}
+		}
+	}
+}
+
+// ignoredColumns returns the lower case names of the columns to leave out for the table.
+func ignoredColumns(schema string, table string, info *model.TableInfo) map[string]struct{} {
+	names, ok := ignoreColumns[strings.ToLower(schema+"."+table)]
+	if !ok {
+		return nil
+	}
+
+	ignored := make(map[string]struct{}, len(names))
+	for name := range names {
+		ignored[name] = struct{}{}
+	}
+	for _, col := range info.Columns {
+		if mysql.HasPriKeyFlag(col.Flag) {
+			delete(ignored, col.Name.L)
+		}
+	}
+	return ignored
+}
+
+func isIgnoredColumn(ignored map[string]struct{}, name string) bool {
+	_, ok := ignored[strings.ToLower(name)]
+	return ok
+}
+
+// DeadLetterFunc receives the row failed to be translated and the cause of it.
+type DeadLetterFunc func(schema, table string, row []byte, err error)
+
+// SetDeadLetter set the function to receive the rows failed to be translated,
+// these rows are dropped from the txn instead of failing the whole translation.
+// Pass nil to fail on such rows, which is the default.
+func SetDeadLetter(fn DeadLetterFunc) {
+	deadLetter = fn
+}
+
+// SetZeroDatePolicy set how to translate zero or partial dates,
+// minDate is used when the value is translated into the minimum date.
+func SetZeroDatePolicy(policy ZeroDatePolicy, minDate string) error {
+	if policy != ZeroDateKeep {
+		t, err := types.ParseTime(new(stmtctx.StatementContext), minDate, mysql.TypeDatetime, types.MaxFsp)
+		if err != nil {
+			return errors.Annotatef(err, "invalid minimum date: %s", minDate)
+		}
+		if t.InvalidZero() {
+			return errors.Errorf("minimum date can't be zero: %s", minDate)
+		}
+		zeroDateMin = t
+	}
+
+	zeroDatePolicy = policy
+	return nil
+}
+
+// SetZeroFillPadding set whether to translate the value of a ZEROFILL integer column
+// into the string padded with zeros to the display width, like `00042` for INT(5) ZEROFILL.
+func SetZeroFillPadding(b bool) {
+	zeroFillPadding = b
+}
+
+// SetEnumByName set whether to translate the value of an ENUM column into the element name
+// instead of the index. The index picks the wrong element if the downstream declares
+// the elements in another order, while the name is looked up in the downstream order.
+func SetEnumByName(b bool) {
+	enumByName = b
+}
+
 func genMysqlInsert(schema string, ptable, table *model.TableInfo, row []byte) (names []string, args []interface{}, err error) {
 	columns := writableColumns(table)
 
-	columnValues, err := insertRowToDatums(table, row)
+	columnValues, err := insertRowToDatums(ptable, table, row)
 	if err != nil {
 		return nil, nil, errors.Trace(err)
 	}
@@ -64,6 +190,10 @@ func genMysqlUpdate(schema string, ptable, table *model.TableInfo, row []byte, c
 
 	var updateColumns []*model.ColumnInfo
 
+	if err = checkColumnIDs(ptable, table, row); err != nil {
+		return nil, nil, nil, errors.Annotatef(err, "table `%s`.`%s`", schema, table.Name)
+	}
+
 	oldColumnValues, newColumnValues, err := updtDecoder.decode(row, time.Local)
 	if err != nil {
 		return nil, nil, nil, errors.Annotatef(err, "table `%s`.`%s`", schema, table.Name)
@@ -84,10 +214,14 @@ func genMysqlUpdate(schema string, ptable, table *model.TableInfo, row []byte, c
 	return
 }
 
-func genMysqlDelete(schema string, table *model.TableInfo, row []byte) (names []string, values []interface{}, err error) {
+func genMysqlDelete(schema string, ptable, table *model.TableInfo, row []byte) (names []string, values []interface{}, err error) {
 	columns := table.Columns
 	colsTypeMap := util.ToColumnTypeMap(columns)
 
+	if err = checkColumnIDs(ptable, table, row); err != nil {
+		return nil, nil, errors.Annotatef(err, "table `%s`.`%s`", schema, table.Name)
+	}
+
 	columnValues, err := tablecodec.DecodeRowToDatumMap(row, colsTypeMap, time.Local)
 	if err != nil {
 		return nil, nil, errors.Trace(err)
@@ -103,16 +237,33 @@ func genMysqlDelete(schema string, table *model.TableInfo, row []byte) (names []
 	return
 }
 
-// TiBinlogToTxn translate the format to loader.Txn
-func TiBinlogToTxn(infoGetter TableInfoGetter, schema string, table string, tiBinlog *tipb.Binlog, pv *tipb.PrewriteValue, shouldSkip bool) (txn *loader.Txn, err error) {
+// TiBinlogToTxn translate the format to loader.Txn,
+// the schemas and tables are renamed by tableRouter unless it's nil.
+func TiBinlogToTxn(infoGetter TableInfoGetter, schema string, table string, tiBinlog *tipb.Binlog, pv *tipb.PrewriteValue, shouldSkip bool, tableRouter *TableRouter) (txn *loader.Txn, err error) {
 	txn = new(loader.Txn)
 
 	if tiBinlog.DdlJobId > 0 {
+		sql, skip, err := translateDDL(string(tiBinlog.GetDdlQuery()))
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		sql, mergedSkip, err := tableRouter.routeDDL(sql, schema)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if mergedSkip {
+			log.Warn("skip ddl dropping a target shared by more than one schema or table", zap.String("sql", sql))
+		}
+		targetSchema, targetTable, err := tableRouter.routeTable(schema, table)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
 		txn.DDL = &loader.DDL{
-			Database:   schema,
-			Table:      table,
-			SQL:        string(tiBinlog.GetDdlQuery()),
-			ShouldSkip: shouldSkip,
+			Database:   targetSchema,
+			Table:      targetTable,
+			SQL:        sql,
+			ShouldSkip: shouldSkip || skip || mergedSkip,
+			CommitTS:   tiBinlog.GetCommitTs(),
 		}
 	} else {
 		for _, mut := range pv.GetMutations() {
@@ -131,6 +282,11 @@ func TiBinlogToTxn(infoGetter TableInfoGetter, schema string, table string, tiBi
 			if !ok {
 				return nil, errors.Errorf("SchemaAndTableName empty table id: %d", mut.GetTableId())
 			}
+			ignored := ignoredColumns(schema, table, info)
+			targetSchema, targetTable, err := tableRouter.routeTable(schema, table)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
 
 			iter := newSequenceIterator(&mut)
 			for {
@@ -146,52 +302,76 @@ func TiBinlogToTxn(infoGetter TableInfoGetter, schema string, table string, tiBi
 				case tipb.MutationType_Insert:
 					names, args, err := genMysqlInsert(schema, pinfo, info, row)
 					if err != nil {
-						return nil, errors.Annotate(err, "gen insert fail")
+						err = errors.Annotate(err, "gen insert fail")
+						if deadLetter == nil {
+							return nil, err
+						}
+						deadLetter(schema, table, row, err)
+						continue
 					}
 
 					dml := &loader.DML{
 						Tp:       loader.InsertDMLType,
-						Database: schema,
-						Table:    table,
+						Database: targetSchema,
+						Table:    targetTable,
 						Values:   make(map[string]interface{}),
 					}
 					txn.DMLs = append(txn.DMLs, dml)
 					for i, name := range names {
+						if isIgnoredColumn(ignored, name) {
+							continue
+						}
 						dml.Values[name] = args[i]
 					}
 				case tipb.MutationType_Update:
 					names, args, oldArgs, err := genMysqlUpdate(schema, pinfo, info, row, canAppendDefaultValue)
 					if err != nil {
-						return nil, errors.Annotate(err, "gen update fail")
+						err = errors.Annotate(err, "gen update fail")
+						if deadLetter == nil {
+							return nil, err
+						}
+						deadLetter(schema, table, row, err)
+						continue
 					}
 
 					dml := &loader.DML{
 						Tp:        loader.UpdateDMLType,
-						Database:  schema,
-						Table:     table,
+						Database:  targetSchema,
+						Table:     targetTable,
 						Values:    make(map[string]interface{}),
 						OldValues: make(map[string]interface{}),
 					}
 					txn.DMLs = append(txn.DMLs, dml)
 					for i, name := range names {
+						if isIgnoredColumn(ignored, name) {
+							continue
+						}
 						dml.Values[name] = args[i]
 						dml.OldValues[name] = oldArgs[i]
 					}
 
 				case tipb.MutationType_DeleteRow:
-					names, args, err := genMysqlDelete(schema, info, row)
+					names, args, err := genMysqlDelete(schema, pinfo, info, row)
 					if err != nil {
-						return nil, errors.Annotate(err, "gen delete fail")
+						err = errors.Annotate(err, "gen delete fail")
+						if deadLetter == nil {
+							return nil, err
+						}
+						deadLetter(schema, table, row, err)
+						continue
 					}
 
 					dml := &loader.DML{
 						Tp:       loader.DeleteDMLType,
-						Database: schema,
-						Table:    table,
+						Database: targetSchema,
+						Table:    targetTable,
 						Values:   make(map[string]interface{}),
 					}
 					txn.DMLs = append(txn.DMLs, dml)
 					for i, name := range names {
+						if isIgnoredColumn(ignored, name) {
+							continue
+						}
 						dml.Values[name] = args[i]
 					}
 
@@ -251,10 +431,37 @@ func formatData(data types.Datum, ft types.FieldType) (types.Datum, error) {
 	}
 
 	switch ft.Tp {
-	case mysql.TypeDate, mysql.TypeDatetime, mysql.TypeNewDate, mysql.TypeTimestamp, mysql.TypeDuration, mysql.TypeNewDecimal, mysql.TypeJSON:
+	case mysql.TypeDate, mysql.TypeDatetime, mysql.TypeNewDate, mysql.TypeTimestamp:
+		// a missing column is filled with its default value as a string, parse it
+		// so the zero date policy applies. The default of a TIMESTAMP column is
+		// in the local time zone as well.
+		if data.Kind() == types.KindString {
+			t, err := types.ParseTime(&stmtctx.StatementContext{TimeZone: time.Local}, data.GetString(), ft.Tp, int8(ft.Decimal))
+			if err == nil {
+				data = types.NewTimeDatum(t)
+			}
+		}
+		if data.Kind() != types.KindMysqlTime {
+			data = types.NewDatum(fmt.Sprintf("%v", data.GetValue()))
+			break
+		}
+
+		t := data.GetMysqlTime()
+		// the row is decoded in the local time zone
+		if ft.Tp == mysql.TypeTimestamp && timeZone != nil && !t.IsZero() {
+			if err := t.ConvertTimeZone(time.Local, timeZone); err != nil {
+				return types.Datum{}, errors.Annotatef(err, "convert %s to time zone %s", t, timeZone)
+			}
+		}
+		data = formatTime(t, ft)
+	case mysql.TypeDuration, mysql.TypeNewDecimal, mysql.TypeJSON:
 		data = types.NewDatum(fmt.Sprintf("%v", data.GetValue()))
 	case mysql.TypeEnum:
-		data = types.NewDatum(data.GetMysqlEnum().Value)
+		if enumByName {
+			data = types.NewDatum(data.GetMysqlEnum().Name)
+		} else {
+			data = types.NewDatum(data.GetMysqlEnum().Value)
+		}
 	case mysql.TypeSet:
 		data = types.NewDatum(data.GetMysqlSet().Value)
 	case mysql.TypeBit:
@@ -264,7 +471,49 @@ func formatData(data types.Datum, ft types.FieldType) (types.Datum, error) {
 			return types.Datum{}, err
 		}
 		data = types.NewUintDatum(val)
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong:
+		// the value of an unsigned column is decoded as int64 if it's encoded as signed,
+		// it must be reinterpreted or values above MaxInt64 turn negative downstream.
+		if mysql.HasUnsignedFlag(ft.Flag) && data.Kind() == types.KindInt64 {
+			data = types.NewUintDatum(uint64(data.GetInt64()))
+		}
+		if zeroFillPadding && mysql.HasZerofillFlag(ft.Flag) && ft.Flen > 0 {
+			// a missing column is filled with its default value as a string
+			if data.Kind() == types.KindString {
+				if val, err := strconv.ParseUint(data.GetString(), 10, 64); err == nil {
+					data = types.NewUintDatum(val)
+				}
+			}
+			if data.Kind() == types.KindInt64 || data.Kind() == types.KindUint64 {
+				data = types.NewDatum(fmt.Sprintf("%0*d", ft.Flen, data.GetValue()))
+			}
+		}
+	case mysql.TypeFloat, mysql.TypeDouble:
+		// MySQL can't store NaN or Inf, and an unsigned float column never
+		// holds a negative value, so either one means the row is corrupted.
+		val := data.GetFloat64()
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			return types.Datum{}, errors.Errorf("invalid float value: %v", val)
+		}
+		if mysql.HasUnsignedFlag(ft.Flag) && val < 0 {
+			return types.Datum{}, errors.Errorf("negative value %v for unsigned float column", val)
+		}
 	}
 
 	return data, nil
 }
+
+func formatTime(t types.Time, ft types.FieldType) types.Datum {
+	if zeroDatePolicy == ZeroDateKeep || !t.InvalidZero() {
+		return types.NewDatum(t.String())
+	}
+
+	if zeroDatePolicy == ZeroDateToNull && !mysql.HasNotNullFlag(ft.Flag) {
+		return types.NewDatum(nil)
+	}
+
+	min := zeroDateMin
+	min.SetType(t.Type())
+	min.SetFsp(t.Fsp())
+	return types.NewDatum(min.String())
+}
@@ -15,11 +15,15 @@ package translator
 
 import (
 	"fmt"
+	"math"
+	"time"
 
 	"github.com/pingcap/check"
 	"github.com/pingcap/tidb-binlog/pkg/loader"
 	"github.com/pingcap/tidb/parser/model"
 	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tidb/sessionctx/stmtctx"
+	"github.com/pingcap/tidb/tablecodec"
 	"github.com/pingcap/tidb/types"
 )
 
@@ -37,7 +41,7 @@ func (t *testMysqlSuite) TestGenColumnList(c *check.C) {
 func (t *testMysqlSuite) TestDDL(c *check.C) {
 	t.SetDDL()
 
-	txn, err := TiBinlogToTxn(t, t.Schema, t.Table, t.TiBinlog, nil, true)
+	txn, err := TiBinlogToTxn(t, t.Schema, t.Table, t.TiBinlog, nil, true, nil)
 	c.Assert(err, check.IsNil)
 
 	c.Assert(txn, check.DeepEquals, &loader.Txn{
@@ -46,12 +50,13 @@ func (t *testMysqlSuite) TestDDL(c *check.C) {
 			Table:      t.Table,
 			SQL:        string(t.TiBinlog.GetDdlQuery()),
 			ShouldSkip: true,
+			CommitTS:   t.TiBinlog.GetCommitTs(),
 		},
 	})
 }
 
 func (t *testMysqlSuite) testDML(c *check.C, tp loader.DMLType) {
-	txn, err := TiBinlogToTxn(t, t.Schema, t.Table, t.TiBinlog, t.PV, false)
+	txn, err := TiBinlogToTxn(t, t.Schema, t.Table, t.TiBinlog, t.PV, false, nil)
 	c.Assert(err, check.IsNil)
 
 	c.Assert(txn.DMLs, check.HasLen, 1)
@@ -118,3 +123,546 @@ func checkMysqlColumn(c *check.C, col *model.ColumnInfo, myValue interface{}, da
 	myStr := fmt.Sprintf("%v", myValue)
 	c.Assert(myStr, check.Equals, tiStr)
 }
+
+func (t *testMysqlSuite) TestDeadLetter(c *check.C) {
+	defer SetDeadLetter(nil)
+
+	t.SetAllDML(c)
+	corrupt := []byte{0xff, 0x01}
+	t.PV.Mutations[0].UpdatedRows[0] = corrupt
+
+	_, err := TiBinlogToTxn(t, t.Schema, t.Table, t.TiBinlog, t.PV, false, nil)
+	c.Assert(err, check.ErrorMatches, "gen update fail.*")
+
+	expectSchema, expectTable, _ := t.SchemaAndTableName(t.PV.Mutations[0].GetTableId())
+	var rows [][]byte
+	SetDeadLetter(func(schema, table string, row []byte, err error) {
+		c.Assert(schema, check.Equals, expectSchema)
+		c.Assert(table, check.Equals, expectTable)
+		c.Assert(err, check.ErrorMatches, "gen update fail.*")
+		rows = append(rows, row)
+	})
+
+	// the other rows are still translated
+	txn, err := TiBinlogToTxn(t, t.Schema, t.Table, t.TiBinlog, t.PV, false, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(rows, check.DeepEquals, [][]byte{corrupt})
+	c.Assert(txn.DMLs, check.HasLen, 2)
+	c.Assert(txn.DMLs[0].Tp, check.Equals, loader.InsertDMLType)
+	c.Assert(txn.DMLs[1].Tp, check.Equals, loader.DeleteDMLType)
+}
+
+func (t *testMysqlSuite) TestIgnoreColumns(c *check.C) {
+	defer SetIgnoreColumns(nil)
+
+	// the primary key column is kept even if it's listed
+	SetIgnoreColumns(map[string][]string{"TEST.Account": {"name", "id"}})
+	t.SetAllDML(c)
+
+	txn, err := TiBinlogToTxn(t, t.Schema, t.Table, t.TiBinlog, t.PV, false, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(txn.DMLs, check.HasLen, 3)
+	for _, dml := range txn.DMLs {
+		c.Assert(dml.Values, check.HasLen, 2)
+		c.Assert(dml.Values, check.Not(check.HasKey), "NAME")
+		c.Assert(dml.Values, check.HasKey, "ID")
+		c.Assert(dml.OldValues, check.Not(check.HasKey), "NAME")
+	}
+	c.Assert(txn.DMLs[1].OldValues, check.HasLen, 2)
+
+	// other tables are not affected
+	SetIgnoreColumns(map[string][]string{"test.other": {"name"}})
+	txn, err = TiBinlogToTxn(t, t.Schema, t.Table, t.TiBinlog, t.PV, false, nil)
+	c.Assert(err, check.IsNil)
+	for _, dml := range txn.DMLs {
+		c.Assert(dml.Values, check.HasLen, 3)
+	}
+}
+
+func (t *testMysqlSuite) TestFormatUnsignedDouble(c *check.C) {
+	ft := types.NewFieldType(mysql.TypeDouble)
+	ft.Flag |= mysql.UnsignedFlag
+
+	data, err := formatData(types.NewFloat64Datum(1.7976931348623157e308), *ft)
+	c.Assert(err, check.IsNil)
+	c.Assert(data.GetValue(), check.Equals, 1.7976931348623157e308)
+
+	_, err = formatData(types.NewFloat64Datum(-1), *ft)
+	c.Assert(err, check.ErrorMatches, ".*negative value.*")
+
+	_, err = formatData(types.NewFloat64Datum(math.NaN()), *ft)
+	c.Assert(err, check.ErrorMatches, ".*invalid float value.*")
+
+	_, err = formatData(types.NewFloat64Datum(math.Inf(1)), *ft)
+	c.Assert(err, check.ErrorMatches, ".*invalid float value.*")
+
+	// negative values are fine for signed columns
+	ft = types.NewFieldType(mysql.TypeDouble)
+	data, err = formatData(types.NewFloat64Datum(-1), *ft)
+	c.Assert(err, check.IsNil)
+	c.Assert(data.GetValue(), check.Equals, float64(-1))
+}
+
+func (t *testMysqlSuite) TestFormatUnsignedBigint(c *check.C) {
+	ft := types.NewFieldType(mysql.TypeLonglong)
+	ft.Flag |= mysql.UnsignedFlag
+
+	for _, datum := range []types.Datum{types.NewUintDatum(math.MaxUint64), types.NewIntDatum(-1)} {
+		data, err := formatData(datum, *ft)
+		c.Assert(err, check.IsNil)
+		c.Assert(data.GetValue(), check.Equals, uint64(18446744073709551615))
+	}
+
+	// signed columns keep the negative value
+	data, err := formatData(types.NewIntDatum(-1), *types.NewFieldType(mysql.TypeLonglong))
+	c.Assert(err, check.IsNil)
+	c.Assert(data.GetValue(), check.Equals, int64(-1))
+}
+
+func (t *testMysqlSuite) TestFormatZeroScaleDecimal(c *check.C) {
+	ft := types.NewFieldType(mysql.TypeNewDecimal)
+	ft.Flen = 20
+	ft.Decimal = 0
+
+	// larger than both MaxInt64 and the exact integer range of float64
+	for _, str := range []string{"12345678901234567891", "99999999999999999999", "-99999999999999999999"} {
+		dec := new(types.MyDecimal)
+		err := dec.FromString([]byte(str))
+		c.Assert(err, check.IsNil)
+
+		data, err := formatData(types.NewDecimalDatum(dec), *ft)
+		c.Assert(err, check.IsNil)
+		c.Assert(data.GetValue(), check.Equals, str)
+	}
+}
+
+func (t *testMysqlSuite) TestFormatHighScaleDecimal(c *check.C) {
+	ft := types.NewFieldType(mysql.TypeNewDecimal)
+	ft.Flen = 65
+	ft.Decimal = 9
+
+	// far beyond the precision of float64
+	dec := new(types.MyDecimal)
+	err := dec.FromString([]byte("12345678901234567890.123456789"))
+	c.Assert(err, check.IsNil)
+
+	data, err := formatData(types.NewDecimalDatum(dec), *ft)
+	c.Assert(err, check.IsNil)
+	c.Assert(data.GetValue(), check.Equals, "12345678901234567890.123456789")
+}
+
+func (t *testMysqlSuite) TestFormatBinary(c *check.C) {
+	ft := types.NewFieldType(mysql.TypeVarchar)
+	ft.Charset = "binary"
+	ft.Collate = "binary"
+	ft.Flag |= mysql.BinaryFlag
+
+	// binary values stay []byte, never converted into string
+	value := []byte{'a', 0x00, 0xff, 0x00}
+	data, err := formatData(types.NewBytesDatum(value), *ft)
+	c.Assert(err, check.IsNil)
+	c.Assert(data.GetValue(), check.DeepEquals, value)
+}
+
+func (t *testMysqlSuite) TestFormatBitAndBlob(c *check.C) {
+	// bits are sent as integer, so the leading zero bits are never lost
+	ft := types.NewFieldType(mysql.TypeBit)
+	ft.Flen = 8
+	bit := types.NewBinaryLiteralFromUint(1, 1)
+	data, err := formatData(types.NewMysqlBitDatum(bit), *ft)
+	c.Assert(err, check.IsNil)
+	c.Assert(data.GetValue(), check.Equals, uint64(1))
+
+	ft = types.NewFieldType(mysql.TypeBlob)
+	ft.Charset = "binary"
+	ft.Collate = "binary"
+	ft.Flag |= mysql.BinaryFlag
+	value := []byte{0x00, 'a', 0x00, 0x00}
+	data, err = formatData(types.NewBytesDatum(value), *ft)
+	c.Assert(err, check.IsNil)
+	c.Assert(data.GetValue(), check.DeepEquals, value)
+}
+
+func (t *testMysqlSuite) TestFormatEnumAndSet(c *check.C) {
+	// MySQL takes the number as the 1-based index of an ENUM element, or as the
+	// bitmap of the SET elements, so they are sent as numbers which never depend
+	// on the charset or collation of the element names.
+	ft := types.NewFieldType(mysql.TypeEnum)
+	ft.Elems = []string{"a", "b", "c"}
+	enum, err := types.ParseEnumName(ft.Elems, "c", mysql.DefaultCollationName)
+	c.Assert(err, check.IsNil)
+	data, err := formatData(types.NewMysqlEnumDatum(enum), *ft)
+	c.Assert(err, check.IsNil)
+	c.Assert(data.GetValue(), check.Equals, uint64(3))
+
+	ft = types.NewFieldType(mysql.TypeSet)
+	ft.Elems = []string{"a", "b", "c"}
+	set, err := types.ParseSetName(ft.Elems, "a,c", mysql.DefaultCollationName)
+	c.Assert(err, check.IsNil)
+	data, err = formatData(types.NewMysqlSetDatum(set, mysql.DefaultCollationName), *ft)
+	c.Assert(err, check.IsNil)
+	c.Assert(data.GetValue(), check.Equals, uint64(5))
+}
+
+func (t *testMysqlSuite) TestGeneratedColumnsNotWritten(c *check.C) {
+	newCol := func(id int64, name string, expr string, stored bool) *model.ColumnInfo {
+		return &model.ColumnInfo{
+			ID:                  id,
+			Name:                model.NewCIStr(name),
+			Offset:              int(id - 1),
+			FieldType:           *types.NewFieldType(mysql.TypeLong),
+			GeneratedExprString: expr,
+			GeneratedStored:     stored,
+			State:               model.StatePublic,
+		}
+	}
+	table := &model.TableInfo{
+		Name: model.NewCIStr("t"),
+		Columns: []*model.ColumnInfo{
+			newCol(1, "id", "", false),
+			newCol(2, "a", "", false),
+			newCol(3, "v", "`a` + 1", false),
+			newCol(4, "s", "`a` * 2", true),
+		},
+	}
+	row := []types.Datum{types.NewIntDatum(1), types.NewIntDatum(2), types.NewIntDatum(3), types.NewIntDatum(4)}
+
+	names, args, err := genMysqlInsert("test", table, table, testGenInsertBinlog(c, table, row))
+	c.Assert(err, check.IsNil)
+	c.Assert(names, check.DeepEquals, []string{"id", "a"})
+	c.Assert(args, check.DeepEquals, []interface{}{int64(1), int64(2)})
+
+	newRow := []types.Datum{types.NewIntDatum(1), types.NewIntDatum(5), types.NewIntDatum(6), types.NewIntDatum(10)}
+	names, values, oldValues, err := genMysqlUpdate("test", table, table, testGenUpdateBinlog(c, table, row, newRow), false)
+	c.Assert(err, check.IsNil)
+	c.Assert(names, check.DeepEquals, []string{"id", "a"})
+	c.Assert(values, check.DeepEquals, []interface{}{int64(1), int64(5)})
+	c.Assert(oldValues, check.DeepEquals, []interface{}{int64(1), int64(2)})
+}
+
+func (t *testMysqlSuite) TestRejectUnknownColumnIDs(c *check.C) {
+	defer SetStrictColumnIDs(false)
+
+	// the row is written with column c3 which was added after the schema snapshot
+	current := testGenTable("normal")
+	stale := testGenTable("normal")
+	stale.Columns = stale.Columns[:len(stale.Columns)-1]
+	unknownID := current.Columns[len(current.Columns)-1].ID
+
+	row := testGenRandomDatums(c, current.Columns)
+	// the values are dropped unless it's strict
+	names, _, err := genMysqlDelete("test", nil, stale, testGenDeleteBinlog(c, current, row))
+	c.Assert(err, check.IsNil)
+	c.Assert(names, check.HasLen, len(stale.Columns))
+
+	SetStrictColumnIDs(true)
+	_, _, err = genMysqlDelete("test", nil, stale, testGenDeleteBinlog(c, current, row))
+	c.Assert(err, check.ErrorMatches, fmt.Sprintf(".*column ids \\[%d\\] of the row are not in table.*", unknownID))
+
+	_, _, _, err = genMysqlUpdate("test", stale, stale, testGenUpdateBinlog(c, current, row, row), false)
+	c.Assert(err, check.ErrorMatches, ".*the schema may be stale.*")
+
+	_, _, err = genMysqlInsert("test", stale, stale, testGenInsertBinlog(c, current, row))
+	c.Assert(err, check.ErrorMatches, ".*the schema may be stale.*")
+
+	// the row is written by a transaction started before c3 is dropped
+	names, _, err = genMysqlDelete("test", current, stale, testGenDeleteBinlog(c, current, row))
+	c.Assert(err, check.IsNil)
+	c.Assert(names, check.HasLen, len(stale.Columns))
+	_, _, _, err = genMysqlUpdate("test", current, stale, testGenUpdateBinlog(c, current, row, row), false)
+	c.Assert(err, check.IsNil)
+
+	// the handle carried by the rows of a table without primary key is never unknown
+	colIDs := make([]int64, 0, len(current.Columns)+1)
+	for _, col := range current.Columns {
+		colIDs = append(colIDs, col.ID)
+	}
+	colIDs = append(colIDs, model.ExtraHandleID)
+	sc := &stmtctx.StatementContext{TimeZone: time.Local}
+	data, err := tablecodec.EncodeOldRow(sc, append(row, types.NewIntDatum(1)), colIDs, nil, nil)
+	c.Assert(err, check.IsNil)
+	names, _, err = genMysqlDelete("test", nil, current, data)
+	c.Assert(err, check.IsNil)
+	c.Assert(names, check.HasLen, len(current.Columns))
+}
+
+func (t *testMysqlSuite) TestMissingTimeColumnWithDefault(c *check.C) {
+	id := &model.ColumnInfo{
+		ID:        1,
+		Name:      model.NewCIStr("id"),
+		FieldType: *types.NewFieldType(mysql.TypeLong),
+		State:     model.StatePublic,
+	}
+	d := &model.ColumnInfo{
+		ID:        2,
+		Name:      model.NewCIStr("d"),
+		Offset:    1,
+		FieldType: *types.NewFieldType(mysql.TypeDatetime),
+		State:     model.StatePublic,
+	}
+	err := d.SetOriginDefaultValue("2021-01-02 03:04:05")
+	c.Assert(err, check.IsNil)
+
+	// the row is written before d is added
+	old := &model.TableInfo{Name: model.NewCIStr("t"), Columns: []*model.ColumnInfo{id}}
+	table := &model.TableInfo{Name: model.NewCIStr("t"), Columns: []*model.ColumnInfo{id, d}}
+	row := testGenInsertBinlog(c, old, []types.Datum{types.NewIntDatum(1)})
+
+	names, args, err := genMysqlInsert("test", table, table, row)
+	c.Assert(err, check.IsNil)
+	c.Assert(names, check.DeepEquals, []string{"id", "d"})
+	c.Assert(args, check.DeepEquals, []interface{}{int64(1), "2021-01-02 03:04:05"})
+}
+
+func (t *testMysqlSuite) TestMissingZeroFillColumnWithDefault(c *check.C) {
+	defer SetZeroFillPadding(false)
+
+	id := &model.ColumnInfo{
+		ID:        1,
+		Name:      model.NewCIStr("id"),
+		FieldType: *types.NewFieldType(mysql.TypeLong),
+		State:     model.StatePublic,
+	}
+	n := &model.ColumnInfo{
+		ID:        2,
+		Name:      model.NewCIStr("n"),
+		Offset:    1,
+		FieldType: *types.NewFieldType(mysql.TypeLong),
+		State:     model.StatePublic,
+	}
+	n.Flag |= mysql.ZerofillFlag | mysql.UnsignedFlag
+	n.Flen = 5
+	err := n.SetOriginDefaultValue("42")
+	c.Assert(err, check.IsNil)
+
+	// the row is written before n is added
+	old := &model.TableInfo{Name: model.NewCIStr("t"), Columns: []*model.ColumnInfo{id}}
+	table := &model.TableInfo{Name: model.NewCIStr("t"), Columns: []*model.ColumnInfo{id, n}}
+	row := testGenInsertBinlog(c, old, []types.Datum{types.NewIntDatum(1)})
+
+	SetZeroFillPadding(true)
+	_, args, err := genMysqlInsert("test", table, table, row)
+	c.Assert(err, check.IsNil)
+	c.Assert(args, check.DeepEquals, []interface{}{int64(1), "00042"})
+}
+
+func (t *testMysqlSuite) TestMissingZeroDateColumnWithDefault(c *check.C) {
+	defer func() {
+		err := SetZeroDatePolicy(ZeroDateKeep, "")
+		c.Assert(err, check.IsNil)
+	}()
+
+	id := &model.ColumnInfo{
+		ID:        1,
+		Name:      model.NewCIStr("id"),
+		FieldType: *types.NewFieldType(mysql.TypeLong),
+		State:     model.StatePublic,
+	}
+	d := &model.ColumnInfo{
+		ID:        2,
+		Name:      model.NewCIStr("d"),
+		Offset:    1,
+		FieldType: *types.NewFieldType(mysql.TypeDate),
+		State:     model.StatePublic,
+	}
+	err := d.SetOriginDefaultValue("0000-00-00")
+	c.Assert(err, check.IsNil)
+
+	// the row is written before d is added
+	old := &model.TableInfo{Name: model.NewCIStr("t"), Columns: []*model.ColumnInfo{id}}
+	table := &model.TableInfo{Name: model.NewCIStr("t"), Columns: []*model.ColumnInfo{id, d}}
+	row := testGenInsertBinlog(c, old, []types.Datum{types.NewIntDatum(1)})
+
+	tests := []struct {
+		policy   ZeroDatePolicy
+		expected interface{}
+	}{
+		{ZeroDateKeep, "0000-00-00"},
+		{ZeroDateToNull, nil},
+		{ZeroDateToMin, "1970-01-01"},
+	}
+	for _, test := range tests {
+		err = SetZeroDatePolicy(test.policy, "1970-01-01")
+		c.Assert(err, check.IsNil)
+
+		_, args, err := genMysqlInsert("test", table, table, row)
+		c.Assert(err, check.IsNil)
+		c.Assert(args, check.DeepEquals, []interface{}{int64(1), test.expected}, check.Commentf("policy: %d", test.policy))
+	}
+}
+
+func (t *testMysqlSuite) TestFormatEnumByName(c *check.C) {
+	defer SetEnumByName(false)
+
+	// upstream declares ('a','b','c') while downstream declares ('c','b','a'),
+	// sending the index 3 would store 'a' downstream.
+	ft := types.NewFieldType(mysql.TypeEnum)
+	ft.Elems = []string{"a", "b", "c"}
+	enum, err := types.ParseEnumName(ft.Elems, "c", mysql.DefaultCollationName)
+	c.Assert(err, check.IsNil)
+
+	SetEnumByName(true)
+	data, err := formatData(types.NewMysqlEnumDatum(enum), *ft)
+	c.Assert(err, check.IsNil)
+	c.Assert(data.GetValue(), check.Equals, "c")
+
+	downstream := []string{"c", "b", "a"}
+	stored, err := types.ParseEnumName(downstream, data.GetString(), mysql.DefaultCollationName)
+	c.Assert(err, check.IsNil)
+	c.Assert(stored.Name, check.Equals, "c")
+	c.Assert(stored.Value, check.Equals, uint64(1))
+}
+
+func (t *testMysqlSuite) TestFormatTimestampInTimeZone(c *check.C) {
+	defer SetTimeZone(nil)
+
+	newYork, err := time.LoadLocation("America/New_York")
+	c.Assert(err, check.IsNil)
+
+	tests := []struct {
+		loc    *time.Location
+		utc    time.Time
+		expect string
+	}{
+		{time.FixedZone("", 8*3600), time.Date(2021, 3, 13, 20, 30, 0, 0, time.UTC), "2021-03-14 04:30:00"},
+		// around the start of the daylight saving time
+		{newYork, time.Date(2021, 3, 14, 6, 59, 59, 0, time.UTC), "2021-03-14 01:59:59"},
+		{newYork, time.Date(2021, 3, 14, 7, 0, 0, 0, time.UTC), "2021-03-14 03:00:00"},
+	}
+
+	for _, test := range tests {
+		SetTimeZone(test.loc)
+		local := test.utc.In(time.Local)
+		datum := func(tp byte) types.Datum {
+			return types.NewTimeDatum(types.NewTime(types.FromGoTime(local), tp, 0))
+		}
+
+		data, err := formatData(datum(mysql.TypeTimestamp), *types.NewFieldType(mysql.TypeTimestamp))
+		c.Assert(err, check.IsNil)
+		c.Assert(data.GetValue(), check.Equals, test.expect, check.Commentf("utc: %s", test.utc))
+
+		// DATETIME is kept as it is
+		data, err = formatData(datum(mysql.TypeDatetime), *types.NewFieldType(mysql.TypeDatetime))
+		c.Assert(err, check.IsNil)
+		c.Assert(data.GetValue(), check.Equals, local.Format("2006-01-02 15:04:05"))
+	}
+}
+
+func (t *testMysqlSuite) TestMissingTimestampColumnWithDefault(c *check.C) {
+	defer SetTimeZone(nil)
+
+	id := &model.ColumnInfo{
+		ID:        1,
+		Name:      model.NewCIStr("id"),
+		FieldType: *types.NewFieldType(mysql.TypeLong),
+		State:     model.StatePublic,
+	}
+	ts := &model.ColumnInfo{
+		ID:        2,
+		Name:      model.NewCIStr("ts"),
+		Offset:    1,
+		FieldType: *types.NewFieldType(mysql.TypeTimestamp),
+		State:     model.StatePublic,
+	}
+	// the default of a TIMESTAMP column is stored in UTC
+	utc := time.Date(2021, 3, 13, 20, 30, 0, 0, time.UTC)
+	err := ts.SetOriginDefaultValue(utc.Format("2006-01-02 15:04:05"))
+	c.Assert(err, check.IsNil)
+
+	old := &model.TableInfo{Name: model.NewCIStr("t"), Columns: []*model.ColumnInfo{id}}
+	table := &model.TableInfo{Name: model.NewCIStr("t"), Columns: []*model.ColumnInfo{id, ts}}
+	row := testGenInsertBinlog(c, old, []types.Datum{types.NewIntDatum(1)})
+
+	for _, loc := range []*time.Location{nil, time.FixedZone("", 8*3600)} {
+		SetTimeZone(loc)
+		expect := utc.In(time.Local)
+		if loc != nil {
+			expect = utc.In(loc)
+		}
+
+		_, args, err := genMysqlInsert("test", table, table, row)
+		c.Assert(err, check.IsNil)
+		c.Assert(args, check.DeepEquals, []interface{}{int64(1), expect.Format("2006-01-02 15:04:05")})
+	}
+}
+
+func (t *testMysqlSuite) TestFormatZeroFill(c *check.C) {
+	defer SetZeroFillPadding(false)
+
+	ft := types.NewFieldType(mysql.TypeLong)
+	ft.Flag |= mysql.ZerofillFlag | mysql.UnsignedFlag
+	ft.Flen = 5
+
+	data, err := formatData(types.NewUintDatum(42), *ft)
+	c.Assert(err, check.IsNil)
+	c.Assert(data.GetValue(), check.Equals, uint64(42))
+
+	SetZeroFillPadding(true)
+	data, err = formatData(types.NewUintDatum(42), *ft)
+	c.Assert(err, check.IsNil)
+	c.Assert(data.GetValue(), check.Equals, "00042")
+
+	// values wider than the display width are kept
+	data, err = formatData(types.NewUintDatum(1234567), *ft)
+	c.Assert(err, check.IsNil)
+	c.Assert(data.GetValue(), check.Equals, "1234567")
+
+	// columns without ZEROFILL are never padded
+	data, err = formatData(types.NewIntDatum(42), *types.NewFieldType(mysql.TypeLong))
+	c.Assert(err, check.IsNil)
+	c.Assert(data.GetValue(), check.Equals, int64(42))
+}
+
+func (t *testMysqlSuite) TestFormatZeroDate(c *check.C) {
+	defer func() {
+		err := SetZeroDatePolicy(ZeroDateKeep, "")
+		c.Assert(err, check.IsNil)
+	}()
+
+	zeroDate := types.NewTime(types.FromDate(0, 0, 0, 0, 0, 0, 0), mysql.TypeDate, 0)
+	partialDate := types.NewTime(types.FromDate(2020, 0, 15, 0, 0, 0, 0), mysql.TypeDate, 0)
+	nullable := *types.NewFieldType(mysql.TypeDate)
+	notNull := *types.NewFieldType(mysql.TypeDate)
+	notNull.Flag |= mysql.NotNullFlag
+
+	tests := []struct {
+		policy   ZeroDatePolicy
+		date     types.Time
+		ft       types.FieldType
+		expected interface{}
+	}{
+		{ZeroDateKeep, zeroDate, nullable, "0000-00-00"},
+		{ZeroDateKeep, partialDate, notNull, "2020-00-15"},
+		{ZeroDateToNull, zeroDate, nullable, nil},
+		{ZeroDateToNull, partialDate, nullable, nil},
+		{ZeroDateToNull, zeroDate, notNull, "1970-01-01"},
+		{ZeroDateToNull, partialDate, notNull, "1970-01-01"},
+		{ZeroDateToMin, zeroDate, nullable, "1970-01-01"},
+		{ZeroDateToMin, partialDate, notNull, "1970-01-01"},
+	}
+
+	for _, test := range tests {
+		err := SetZeroDatePolicy(test.policy, "1970-01-01")
+		c.Assert(err, check.IsNil)
+
+		data, err := formatData(types.NewTimeDatum(test.date), test.ft)
+		c.Assert(err, check.IsNil)
+		c.Assert(data.GetValue(), check.Equals, test.expected, check.Commentf("policy: %d, date: %s", test.policy, test.date))
+	}
+
+	// valid dates are never changed
+	err := SetZeroDatePolicy(ZeroDateToMin, "1970-01-01")
+	c.Assert(err, check.IsNil)
+	date := types.NewTime(types.FromDate(2020, 1, 15, 10, 0, 0, 0), mysql.TypeDatetime, 0)
+	data, err := formatData(types.NewTimeDatum(date), *types.NewFieldType(mysql.TypeDatetime))
+	c.Assert(err, check.IsNil)
+	c.Assert(data.GetValue(), check.Equals, "2020-01-15 10:00:00")
+
+	// the minimum date keeps the type of the column
+	data, err = formatData(types.NewTimeDatum(types.NewTime(types.ZeroCoreTime, mysql.TypeDatetime, 0)), *types.NewFieldType(mysql.TypeDatetime))
+	c.Assert(err, check.IsNil)
+	c.Assert(data.GetValue(), check.Equals, "1970-01-01 00:00:00")
+
+	err = SetZeroDatePolicy(ZeroDateToMin, "0000-00-00")
+	c.Assert(err, check.NotNil)
+}
@@ -100,7 +100,7 @@ func TiBinlogToPbBinlog(infoGetter TableInfoGetter, schema string, table string,
 					pbBinlog.DmlData.Events = append(pbBinlog.DmlData.Events, *event)
 
 				case tipb.MutationType_DeleteRow:
-					event, err := genDelete(schema, info, row)
+					event, err := genDelete(schema, pinfo, info, row)
 					if err != nil {
 						return nil, errors.Annotatef(err, "genDelete failed")
 					}
@@ -119,7 +119,7 @@ func TiBinlogToPbBinlog(infoGetter TableInfoGetter, schema string, table string,
 func genInsert(schema string, ptable, table *model.TableInfo, row []byte) (event *pb.Event, err error) {
 	columns := table.Columns
 
-	columnValues, err := insertRowToDatums(table, row)
+	columnValues, err := insertRowToDatums(ptable, table, row)
 	if err != nil {
 		return nil, errors.Annotatef(err, "table `%s`.`%s`", schema, table.Name)
 	}
@@ -161,6 +161,10 @@ func genUpdate(schema string, ptable, table *model.TableInfo, row []byte, canApp
 	columns := writableColumns(table)
 	colsMap := util.ToColumnMap(columns)
 
+	if err = checkColumnIDs(ptable, table, row); err != nil {
+		return nil, errors.Annotatef(err, "table `%s`.`%s`", schema, table.Name)
+	}
+
 	oldColumnValues, newColumnValues, err := DecodeOldAndNewRow(row, colsMap, time.Local, canAppendDefaultValue, ptable)
 	if err != nil {
 		return nil, errors.Annotatef(err, "table `%s`.`%s`", schema, table.Name)
@@ -202,10 +206,14 @@ func genUpdate(schema string, ptable, table *model.TableInfo, row []byte, canApp
 	return
 }
 
-func genDelete(schema string, table *model.TableInfo, row []byte) (event *pb.Event, err error) {
+func genDelete(schema string, ptable, table *model.TableInfo, row []byte) (event *pb.Event, err error) {
 	columns := table.Columns
 	colsTypeMap := util.ToColumnTypeMap(columns)
 
+	if err = checkColumnIDs(ptable, table, row); err != nil {
+		return nil, errors.Annotatef(err, "table `%s`.`%s`", schema, table.Name)
+	}
+
 	columnValues, err := tablecodec.DecodeRowToDatumMap(row, colsTypeMap, time.Local)
 	if err != nil {
 		return nil, errors.Annotatef(err, "table `%s`.`%s`", schema, table.Name)
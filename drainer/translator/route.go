@@ -0,0 +1,300 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package translator
+
+import (
+	"strings"
+
+	"github.com/pingcap/errors"
+	router "github.com/pingcap/tidb-tools/pkg/table-router"
+	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/format"
+	"github.com/pingcap/tidb/parser/model"
+)
+
+// restoreFlags keeps the TiDB specific clauses in `/*T! ... */` comments,
+// so they're still ignored by MySQL after the DDL is rewritten.
+const restoreFlags = format.DefaultRestoreFlags | format.RestoreTiDBSpecialComment
+
+// TableRouter renames the schemas and tables downstream by the route rules,
+// the table level rules take precedence over the schema level ones.
+// A nil TableRouter keeps the names as they are.
+type TableRouter struct {
+	*router.Table
+
+	caseSensitive bool
+	// the rules routing more than one schema or table into the same target
+	merged map[*router.TableRule]struct{}
+}
+
+// NewTableRouter returns a TableRouter with the rules.
+func NewTableRouter(caseSensitive bool, rules []*router.TableRule) (*TableRouter, error) {
+	r, err := router.NewTableRouter(caseSensitive, rules)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	merged := make(map[*router.TableRule]struct{})
+	for i, rule := range rules {
+		if strings.ContainsAny(rule.SchemaPattern, "*?") ||
+			(len(rule.TargetTable) > 0 && strings.ContainsAny(rule.TablePattern, "*?")) {
+			merged[rule] = struct{}{}
+			continue
+		}
+		for j, other := range rules {
+			if i != j && sameTarget(rule, other, caseSensitive) {
+				merged[rule] = struct{}{}
+				break
+			}
+		}
+	}
+
+	return &TableRouter{Table: r, caseSensitive: caseSensitive, merged: merged}, nil
+}
+
+// sameTarget returns whether the targets of the rules overlap,
+// a rule without target table takes the whole target schema.
+func sameTarget(a, b *router.TableRule, caseSensitive bool) bool {
+	equal := func(x, y string) bool {
+		if caseSensitive {
+			return x == y
+		}
+		return strings.EqualFold(x, y)
+	}
+
+	if !equal(a.TargetSchema, b.TargetSchema) {
+		return false
+	}
+	return len(a.TargetTable) == 0 || len(b.TargetTable) == 0 || equal(a.TargetTable, b.TargetTable)
+}
+
+// routeTable returns the downstream schema and table, table is empty for the schema itself.
+func (r *TableRouter) routeTable(schema string, table string) (string, string, error) {
+	if r == nil {
+		return schema, table, nil
+	}
+
+	targetSchema, targetTable, err := r.Route(schema, table)
+	if err != nil {
+		return "", "", errors.Annotatef(err, "route table `%s`.`%s`", schema, table)
+	}
+	return targetSchema, targetTable, nil
+}
+
+// isMerged returns whether the schema or table is routed into a target shared with others,
+// table is empty for the schema itself.
+func (r *TableRouter) isMerged(schema string, table string) (bool, error) {
+	if r == nil {
+		return false, nil
+	}
+
+	if !r.caseSensitive {
+		schema, table = strings.ToLower(schema), strings.ToLower(table)
+	}
+
+	// pick the rule the same way as Route
+	var schemaRule, tableRule *router.TableRule
+	for _, v := range r.Match(schema, table) {
+		rule, ok := v.(*router.TableRule)
+		if !ok {
+			return false, errors.Errorf("table route rule %+v is invalid", v)
+		}
+		if len(rule.TablePattern) == 0 {
+			schemaRule = rule
+		} else {
+			tableRule = rule
+		}
+	}
+
+	rule := schemaRule
+	if len(table) > 0 && tableRule != nil {
+		rule = tableRule
+	}
+	if rule == nil {
+		return false, nil
+	}
+
+	_, ok := r.merged[rule]
+	return ok, nil
+}
+
+// routeDDL renames the schemas and tables in the DDL, schema is the one to use
+// if a table name doesn't specify the schema. skip is true if the DDL drops or
+// truncates a target shared with other schemas or tables, executing it would
+// wipe out the data of all of them.
+func (r *TableRouter) routeDDL(sql string, schema string) (newSQL string, skip bool, err error) {
+	if r == nil {
+		return sql, false, nil
+	}
+
+	stmt, err := getParser().ParseOneStmt(sql, "", "")
+	if err != nil {
+		return "", false, errors.Annotatef(err, "parse ddl: %s", sql)
+	}
+
+	v := &routeVisitor{router: r, schema: schema}
+	switch node := stmt.(type) {
+	case *ast.CreateDatabaseStmt:
+		node.Name = v.routeSchema(node.Name)
+	case *ast.DropDatabaseStmt:
+		merged, err := r.isMerged(node.Name, "")
+		if err != nil {
+			return "", false, errors.Annotatef(err, "ddl: %s", sql)
+		}
+		if merged {
+			return sql, true, nil
+		}
+		node.Name = v.routeSchema(node.Name)
+	case *ast.AlterDatabaseStmt:
+		if !node.AlterDefaultDatabase {
+			node.Name = v.routeSchema(node.Name)
+		}
+	case *ast.TruncateTableStmt:
+		tableSchema := node.Table.Schema.O
+		if len(tableSchema) == 0 {
+			tableSchema = schema
+		}
+		merged, err := r.isMerged(tableSchema, node.Table.Name.O)
+		if err != nil {
+			return "", false, errors.Annotatef(err, "ddl: %s", sql)
+		}
+		if merged {
+			return sql, true, nil
+		}
+		stmt.Accept(v)
+	case *ast.DropTableStmt:
+		for _, table := range node.Tables {
+			tableSchema := table.Schema.O
+			if len(tableSchema) == 0 {
+				tableSchema = schema
+			}
+			merged, err := r.isMerged(tableSchema, table.Name.O)
+			if err != nil {
+				return "", false, errors.Annotatef(err, "ddl: %s", sql)
+			}
+			if merged {
+				return sql, true, nil
+			}
+		}
+		stmt.Accept(v)
+	case *ast.RenameTableStmt:
+		if err = r.checkRenameRouted(node, schema); err != nil {
+			return "", false, errors.Annotatef(err, "ddl: %s", sql)
+		}
+		stmt.Accept(v)
+	default:
+		stmt.Accept(v)
+	}
+	if v.err != nil {
+		return "", false, errors.Annotatef(v.err, "ddl: %s", sql)
+	}
+
+	if !v.changed {
+		return sql, false, nil
+	}
+
+	var builder strings.Builder
+	if err = stmt.Restore(format.NewRestoreCtx(restoreFlags, &builder)); err != nil {
+		return "", false, errors.Annotatef(err, "restore ddl: %s", sql)
+	}
+
+	return builder.String(), false, nil
+}
+
+// checkRenameRouted makes sure both sides of every rename are routed or neither is,
+// renaming between a routed table and one kept as it is would desync them downstream.
+func (r *TableRouter) checkRenameRouted(stmt *ast.RenameTableStmt, schema string) error {
+	for _, t2t := range stmt.TableToTables {
+		oldRouted, err := r.isTableRouted(t2t.OldTable, schema)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		newRouted, err := r.isTableRouted(t2t.NewTable, schema)
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		if oldRouted != newRouted {
+			return errors.Errorf("only one of %s and %s is routed",
+				quoteTableName(t2t.OldTable, schema), quoteTableName(t2t.NewTable, schema))
+		}
+	}
+
+	return nil
+}
+
+func (r *TableRouter) isTableRouted(table *ast.TableName, defaultSchema string) (bool, error) {
+	schema := table.Schema.O
+	if len(schema) == 0 {
+		schema = defaultSchema
+	}
+
+	targetSchema, targetTable, err := r.routeTable(schema, table.Name.O)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return targetSchema != schema || targetTable != table.Name.O, nil
+}
+
+type routeVisitor struct {
+	router  *TableRouter
+	schema  string
+	changed bool
+	err     error
+}
+
+func (v *routeVisitor) routeSchema(schema string) string {
+	targetSchema, _, err := v.router.routeTable(schema, "")
+	if err != nil {
+		v.err = err
+		return schema
+	}
+
+	if targetSchema != schema {
+		v.changed = true
+	}
+	return targetSchema
+}
+
+// Enter implements ast.Visitor interface.
+func (v *routeVisitor) Enter(in ast.Node) (ast.Node, bool) {
+	table, ok := in.(*ast.TableName)
+	if !ok || v.err != nil {
+		return in, v.err != nil
+	}
+
+	schema := table.Schema.O
+	if len(schema) == 0 {
+		schema = v.schema
+	}
+
+	targetSchema, targetTable, err := v.router.routeTable(schema, table.Name.O)
+	if err != nil {
+		v.err = err
+		return in, true
+	}
+
+	if targetSchema != schema || targetTable != table.Name.O {
+		// always specify the schema, it may not be the one in use downstream
+		table.Schema = model.NewCIStr(targetSchema)
+		table.Name = model.NewCIStr(targetTable)
+		v.changed = true
+	}
+	return in, true
+}
+
+// Leave implements ast.Visitor interface.
+func (v *routeVisitor) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
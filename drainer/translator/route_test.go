@@ -0,0 +1,156 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package translator
+
+import (
+	"github.com/pingcap/check"
+	router "github.com/pingcap/tidb-tools/pkg/table-router"
+)
+
+type testRouteSuite struct {
+	BinlogGenerator
+}
+
+var _ = check.Suite(&testRouteSuite{})
+
+func newRouter(c *check.C, rules ...*router.TableRule) *TableRouter {
+	r, err := NewTableRouter(false, rules)
+	c.Assert(err, check.IsNil)
+	return r
+}
+
+var (
+	schemaRule = &router.TableRule{SchemaPattern: "test", TargetSchema: "analytics"}
+	tableRule  = &router.TableRule{SchemaPattern: "test", TablePattern: "account", TargetSchema: "db2", TargetTable: "t9"}
+)
+
+func (t *testRouteSuite) TestRouteDML(c *check.C) {
+	tests := []struct {
+		rules  []*router.TableRule
+		schema string
+		table  string
+	}{
+		{[]*router.TableRule{schemaRule}, "analytics", "account"},
+		{[]*router.TableRule{tableRule}, "db2", "t9"},
+		// the table rule takes precedence
+		{[]*router.TableRule{schemaRule, tableRule}, "db2", "t9"},
+	}
+
+	for _, test := range tests {
+		r := newRouter(c, test.rules...)
+		t.SetAllDML(c)
+
+		txn, err := TiBinlogToTxn(t, t.Schema, t.Table, t.TiBinlog, t.PV, false, r)
+		c.Assert(err, check.IsNil)
+		c.Assert(txn.DMLs, check.HasLen, 3)
+		for _, dml := range txn.DMLs {
+			c.Assert(dml.Database, check.Equals, test.schema)
+			c.Assert(dml.Table, check.Equals, test.table)
+		}
+	}
+}
+
+func (t *testRouteSuite) TestRouteDDL(c *check.C) {
+	r := newRouter(c, schemaRule, tableRule)
+
+	tests := []struct {
+		sql    string
+		expect string
+	}{
+		{"create database test", "CREATE DATABASE `analytics`"},
+		{"drop database if exists test", "DROP DATABASE IF EXISTS `analytics`"},
+		{"create table t1(id int)", "CREATE TABLE `analytics`.`t1` (`id` INT)"},
+		{"alter table test.account add column a int", "ALTER TABLE `db2`.`t9` ADD COLUMN `a` INT"},
+		{"rename table account to t2", "RENAME TABLE `db2`.`t9` TO `analytics`.`t2`"},
+		{"create table t2 like account", "CREATE TABLE `analytics`.`t2` LIKE `db2`.`t9`"},
+		// names not matching any rule are kept as they are
+		{"create table db1.t1(id int)", "create table db1.t1(id int)"},
+	}
+	for _, test := range tests {
+		sql, skip, err := r.routeDDL(test.sql, "test")
+		c.Assert(err, check.IsNil)
+		c.Assert(sql, check.Equals, test.expect, check.Commentf("sql: %s", test.sql))
+		c.Assert(skip, check.IsFalse, check.Commentf("sql: %s", test.sql))
+	}
+
+	t.SetDDL()
+	txn, err := TiBinlogToTxn(t, t.Schema, t.Table, t.TiBinlog, nil, false, r)
+	c.Assert(err, check.IsNil)
+	c.Assert(txn.DDL.Database, check.Equals, "analytics")
+	c.Assert(txn.DDL.Table, check.Equals, "test")
+	c.Assert(txn.DDL.SQL, check.Equals, "CREATE TABLE `analytics`.`test` (`id` INT)")
+}
+
+func (t *testRouteSuite) TestRouteRenameTable(c *check.C) {
+	r := newRouter(c, tableRule, &router.TableRule{SchemaPattern: "test", TablePattern: "account_old", TargetSchema: "db2", TargetTable: "t9_old"})
+
+	// both sides are renamed downstream
+	sql, _, err := r.routeDDL("rename table account to account_old", "test")
+	c.Assert(err, check.IsNil)
+	c.Assert(sql, check.Equals, "RENAME TABLE `db2`.`t9` TO `db2`.`t9_old`")
+
+	// neither side is routed
+	sql, _, err = r.routeDDL("rename table t1 to t2, db1.t3 to db1.t4", "test")
+	c.Assert(err, check.IsNil)
+	c.Assert(sql, check.Equals, "rename table t1 to t2, db1.t3 to db1.t4")
+
+	// only one side is routed
+	_, _, err = r.routeDDL("rename table account to t2", "test")
+	c.Assert(err, check.ErrorMatches, ".*only one of `test`.`account` and `test`.`t2` is routed.*")
+	_, _, err = r.routeDDL("rename table t1 to t2, t2 to account_old", "test")
+	c.Assert(err, check.ErrorMatches, ".*only one of `test`.`t2` and `test`.`account_old` is routed.*")
+}
+
+func (t *testRouteSuite) TestRouteDDLKeepTiDBComments(c *check.C) {
+	r := newRouter(c, schemaRule)
+
+	sql, _, err := r.routeDDL("create table t1(id bigint primary key /*T![auto_rand] AUTO_RANDOM(5) */) /*T! SHARD_ROW_ID_BITS=4 */", "test")
+	c.Assert(err, check.IsNil)
+	c.Assert(sql, check.Equals, "CREATE TABLE `analytics`.`t1` (`id` BIGINT PRIMARY KEY /*T![auto_rand] AUTO_RANDOM(5) */) /*T! SHARD_ROW_ID_BITS = 4 */")
+}
+
+func (t *testRouteSuite) TestRouteDDLMergedTarget(c *check.C) {
+	tests := []struct {
+		rules []*router.TableRule
+		sql   string
+		skip  bool
+	}{
+		{[]*router.TableRule{{SchemaPattern: "shard_*", TargetSchema: "merged"}}, "drop database shard_01", true},
+		{[]*router.TableRule{{SchemaPattern: "shard_*", TargetSchema: "merged"}}, "truncate table shard_01.t1", true},
+		{[]*router.TableRule{{SchemaPattern: "test", TablePattern: "t_*", TargetSchema: "test", TargetTable: "t"}}, "truncate table t_1", true},
+		{[]*router.TableRule{{SchemaPattern: "test", TablePattern: "t_*", TargetSchema: "test", TargetTable: "t"}}, "drop table t_1", true},
+		{[]*router.TableRule{{SchemaPattern: "shard_*", TargetSchema: "merged"}}, "drop table if exists shard_01.t1", true},
+		// only one of the dropped tables is merged
+		{[]*router.TableRule{{SchemaPattern: "test", TablePattern: "t_*", TargetSchema: "test", TargetTable: "t"}}, "drop table account, t_1", true},
+		// exact rules sharing the same target
+		{[]*router.TableRule{{SchemaPattern: "s1", TargetSchema: "merged"}, {SchemaPattern: "s2", TargetSchema: "merged"}}, "drop database s1", true},
+		// one to one rules
+		{[]*router.TableRule{schemaRule}, "drop database test", false},
+		{[]*router.TableRule{tableRule}, "truncate table account", false},
+		{[]*router.TableRule{{SchemaPattern: "test", TablePattern: "t_*", TargetSchema: "db2"}}, "truncate table t_1", false},
+		{[]*router.TableRule{tableRule}, "drop table account, t_1", false},
+		// other statements are still routed
+		{[]*router.TableRule{{SchemaPattern: "shard_*", TargetSchema: "merged"}}, "create database shard_01", false},
+	}
+
+	for _, test := range tests {
+		r := newRouter(c, test.rules...)
+		sql, skip, err := r.routeDDL(test.sql, "test")
+		c.Assert(err, check.IsNil)
+		c.Assert(skip, check.Equals, test.skip, check.Commentf("sql: %s", test.sql))
+		if test.skip {
+			c.Assert(sql, check.Equals, test.sql)
+		}
+	}
+}
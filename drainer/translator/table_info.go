@@ -13,7 +13,12 @@
 
 package translator
 
-import "github.com/pingcap/tidb/parser/model"
+import (
+	"fmt"
+
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/parser/mysql"
+)
 
 // TableInfoGetter is used to get table info by table id of TiDB
 type TableInfoGetter interface {
@@ -23,3 +28,61 @@ type TableInfoGetter interface {
 	// IsDroppingColumn(id int64) bool
 	TableBySchemaVersion(id int64, schemaVersion int64) (info *model.TableInfo, ok bool)
 }
+
+// CanReplicate checks whether the rows of the table can be replicated correctly
+// to MySQL or TiDB, and returns the reason if not.
+func CanReplicate(table *model.TableInfo) (bool, string) {
+	columns := writableColumns(table)
+	if len(columns) == 0 {
+		return false, "table has no writable column"
+	}
+
+	for _, col := range table.Columns {
+		if col.Tp == mysql.TypeGeometry {
+			return false, fmt.Sprintf("column %s has unsupported type geometry", col.Name.O)
+		}
+	}
+
+	if hasUsableKey(table) {
+		return true, ""
+	}
+
+	// update and delete match the row by all the columns without a key,
+	// which never equal for the approximate or non comparable values.
+	for _, col := range columns {
+		switch col.Tp {
+		case mysql.TypeFloat, mysql.TypeDouble, mysql.TypeJSON:
+			return false, fmt.Sprintf("table has no primary key or unique key without generated column, and column %s can't be matched by value", col.Name.O)
+		}
+	}
+
+	return true, ""
+}
+
+// hasUsableKey checks whether the table has a primary key or unique key
+// to identify the row, the generated columns are not written downstream
+// so the keys containing them can't be used.
+func hasUsableKey(table *model.TableInfo) bool {
+	if table.PKIsHandle {
+		return true
+	}
+
+	for _, index := range table.Indices {
+		if !index.Unique && !index.Primary {
+			continue
+		}
+
+		usable := true
+		for _, idxCol := range index.Columns {
+			if table.Columns[idxCol.Offset].IsGenerated() {
+				usable = false
+				break
+			}
+		}
+		if usable {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package translator
+
+import (
+	"github.com/pingcap/check"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tidb/types"
+)
+
+type testTableInfoSuite struct{}
+
+var _ = check.Suite(&testTableInfoSuite{})
+
+func newColumn(name string, offset int, tp byte) *model.ColumnInfo {
+	return &model.ColumnInfo{
+		Name:      model.NewCIStr(name),
+		Offset:    offset,
+		State:     model.StatePublic,
+		FieldType: *types.NewFieldType(tp),
+	}
+}
+
+func newUniqueIndex(name string, cols ...*model.ColumnInfo) *model.IndexInfo {
+	index := &model.IndexInfo{Name: model.NewCIStr(name), Unique: true}
+	for _, col := range cols {
+		index.Columns = append(index.Columns, &model.IndexColumn{Name: col.Name, Offset: col.Offset})
+	}
+	return index
+}
+
+func (s *testTableInfoSuite) TestCanReplicate(c *check.C) {
+	id := newColumn("id", 0, mysql.TypeLong)
+	score := newColumn("score", 1, mysql.TypeDouble)
+	virtual := newColumn("v", 2, mysql.TypeLong)
+	virtual.GeneratedExprString = "id + 1"
+
+	tests := []struct {
+		name  string
+		table *model.TableInfo
+		ok    bool
+	}{
+		{
+			name:  "int handle",
+			table: &model.TableInfo{Columns: []*model.ColumnInfo{id, score}, PKIsHandle: true},
+			ok:    true,
+		},
+		{
+			name:  "unique key",
+			table: &model.TableInfo{Columns: []*model.ColumnInfo{id, score}, Indices: []*model.IndexInfo{newUniqueIndex("uk", id)}},
+			ok:    true,
+		},
+		{
+			name:  "no key but all columns comparable",
+			table: &model.TableInfo{Columns: []*model.ColumnInfo{id}},
+			ok:    true,
+		},
+		{
+			name:  "no key with double column",
+			table: &model.TableInfo{Columns: []*model.ColumnInfo{id, score}},
+			ok:    false,
+		},
+		{
+			name:  "only key on virtual generated column",
+			table: &model.TableInfo{Columns: []*model.ColumnInfo{id, score, virtual}, Indices: []*model.IndexInfo{newUniqueIndex("uk", virtual)}},
+			ok:    false,
+		},
+		{
+			name:  "geometry column",
+			table: &model.TableInfo{Columns: []*model.ColumnInfo{id, newColumn("g", 1, mysql.TypeGeometry)}, PKIsHandle: true},
+			ok:    false,
+		},
+		{
+			name:  "no writable column",
+			table: &model.TableInfo{Columns: []*model.ColumnInfo{virtual}},
+			ok:    false,
+		},
+	}
+
+	for _, test := range tests {
+		ok, reason := CanReplicate(test.table)
+		c.Assert(ok, check.Equals, test.ok, check.Commentf("table: %s, reason: %s", test.name, reason))
+		if ok {
+			c.Assert(reason, check.Equals, "")
+		} else {
+			c.Assert(reason, check.Not(check.Equals), "", check.Commentf("table: %s", test.name))
+		}
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/pingcap/tidb/tablecodec"
 	"github.com/pingcap/tidb/types"
 	"github.com/pingcap/tidb/util/codec"
+	"github.com/pingcap/tidb/util/rowcodec"
 	"go.uber.org/zap"
 )
 
@@ -37,6 +38,15 @@ func SetSQLMode(mode mysql.SQLMode) {
 	sqlMode = mode
 }
 
+var strictColumnIDs bool
+
+// SetStrictColumnIDs set whether to reject the rows carrying columns in neither the table info
+// of the transaction's schema version nor the latest one, which means the schema is stale.
+// It's off by default and the values of such columns are dropped.
+func SetStrictColumnIDs(strict bool) {
+	strictColumnIDs = strict
+}
+
 func getParser() (p *parser.Parser) {
 	p = parser.New()
 	p.SetSQLMode(sqlMode)
@@ -44,7 +54,7 @@ func getParser() (p *parser.Parser) {
 	return
 }
 
-func insertRowToDatums(table *model.TableInfo, row []byte) (datums map[int64]types.Datum, err error) {
+func insertRowToDatums(ptable, table *model.TableInfo, row []byte) (datums map[int64]types.Datum, err error) {
 	colsTypeMap := util.ToColumnTypeMap(table.Columns)
 
 	var (
@@ -84,6 +94,10 @@ func insertRowToDatums(table *model.TableInfo, row []byte) (datums map[int64]typ
 		pk = append(pk, aPK)
 	}
 
+	if err = checkColumnIDs(ptable, table, remain); err != nil {
+		return nil, errors.Trace(err)
+	}
+
 	datums, err = tablecodec.DecodeRowToDatumMap(remain, colsTypeMap, time.Local)
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -233,6 +247,57 @@ func getDefaultOrZeroValue(tableInfo *model.TableInfo, col *model.ColumnInfo) ty
 	return table.GetZeroValue(col)
 }
 
+// checkColumnIDs makes sure all the columns of the row are in either table if strictColumnIDs
+// is set. Decoding would drop the values of the others silently, which means the schema is stale.
+// ptable is the table info of the transaction's schema version, the rows written around
+// a DROP COLUMN may still carry the dropped column. It may be nil.
+// Row layout: colID1, value1, colID2, value2, .....
+func checkColumnIDs(ptable, table *model.TableInfo, b []byte) error {
+	if !strictColumnIDs || len(b) == 0 || b[0] == codec.NilFlag || rowcodec.IsNewFormat(b) {
+		// binlog rows are always encoded in the old format
+		return nil
+	}
+
+	ids := make(map[int64]struct{}, len(table.Columns)+1)
+	for _, col := range table.Columns {
+		ids[col.ID] = struct{}{}
+	}
+	if ptable != nil {
+		for _, col := range ptable.Columns {
+			ids[col.ID] = struct{}{}
+		}
+	}
+	// the updated and deleted rows carry the handle if it's not the primary key
+	ids[model.ExtraHandleID] = struct{}{}
+
+	var unknown []int64
+	for len(b) > 0 {
+		data, remain, err := codec.CutOne(b)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		_, cid, err := codec.DecodeOne(data)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		// skip the value
+		_, b, err = codec.CutOne(remain)
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		id := cid.GetInt64()
+		if _, ok := ids[id]; !ok {
+			unknown = append(unknown, id)
+		}
+	}
+
+	if len(unknown) > 0 {
+		return errors.Errorf("column ids %v of the row are not in table %s, the schema may be stale", unknown, table.Name)
+	}
+	return nil
+}
+
 // DecodeOldAndNewRow decodes a byte slice into datums with a existing row map.
 // Row layout: colID1, value1, colID2, value2, .....
 func DecodeOldAndNewRow(b []byte,
@@ -17,6 +17,9 @@ import (
 	"testing"
 
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tidb/types"
 )
 
 func TestClient(t *testing.T) {
@@ -26,3 +29,33 @@ func TestClient(t *testing.T) {
 var _ = Suite(&testTranslatorSuite{})
 
 type testTranslatorSuite struct{}
+
+func (s *testTranslatorSuite) TestGetDefaultOrZeroValue(c *C) {
+	newCol := func(tp byte, flag uint) *model.ColumnInfo {
+		col := &model.ColumnInfo{ID: 1, Name: model.NewCIStr("c1"), FieldType: *types.NewFieldType(tp)}
+		col.Flag = flag
+		return col
+	}
+
+	// a nullable column without default value is written as NULL explicitly
+	data := getDefaultOrZeroValue(nil, newCol(mysql.TypeLong, 0))
+	c.Assert(data.IsNull(), IsTrue)
+
+	// a NOT NULL column without default value gets the implicit default of MySQL
+	data = getDefaultOrZeroValue(nil, newCol(mysql.TypeLong, mysql.NotNullFlag))
+	c.Assert(data.GetValue(), Equals, int64(0))
+	data = getDefaultOrZeroValue(nil, newCol(mysql.TypeVarchar, mysql.NotNullFlag))
+	c.Assert(data.GetValue(), Equals, "")
+	enum := newCol(mysql.TypeEnum, mysql.NotNullFlag)
+	enum.Elems = []string{"a", "b"}
+	data = getDefaultOrZeroValue(nil, enum)
+	c.Assert(data.GetValue(), Equals, "a")
+
+	// the origin default value of the column in the table info of the row is used
+	col := newCol(mysql.TypeVarchar, mysql.NotNullFlag)
+	prev := newCol(mysql.TypeVarchar, mysql.NotNullFlag)
+	err := prev.SetOriginDefaultValue("x")
+	c.Assert(err, IsNil)
+	data = getDefaultOrZeroValue(&model.TableInfo{Columns: []*model.ColumnInfo{prev}}, col)
+	c.Assert(data.GetValue(), Equals, "x")
+}
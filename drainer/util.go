@@ -14,6 +14,7 @@
 package drainer
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
 	"net"
@@ -22,6 +23,7 @@ import (
 	"path"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/Shopify/sarama"
 	"github.com/pingcap/errors"
@@ -38,6 +40,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/pingcap/tidb-binlog/drainer/checkpoint"
+	"github.com/pingcap/tidb-binlog/drainer/translator"
 )
 
 const (
@@ -210,7 +213,120 @@ func getParser(sqlMode mysql.SQLMode) (p *parser.Parser) {
 	return
 }
 
-func genRouterAndBinlogEvent(cfg *SyncerConfig) (*router.Table, *bf.BinlogEvent, error) {
+// setTranslatorOptions applies the translation options of the config,
+// they're global to the translator.
+func setTranslatorOptions(cfg *SyncerConfig) error {
+	var timeZone *time.Location
+	if len(cfg.TimeZone) > 0 {
+		var err error
+		if timeZone, err = time.LoadLocation(cfg.TimeZone); err != nil {
+			return errors.Annotate(err, "invalid time-zone")
+		}
+	}
+	translator.SetTimeZone(timeZone)
+
+	zeroDatePolicy, err := parseZeroDatePolicy(cfg.ZeroDatePolicy)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = translator.SetZeroDatePolicy(zeroDatePolicy, cfg.ZeroDateMin); err != nil {
+		return errors.Annotate(err, "invalid zero-date-min")
+	}
+	translator.SetZeroFillPadding(cfg.ZeroFillPadding)
+
+	convertCharsetPolicy, err := parseConvertCharsetPolicy(cfg.ConvertCharsetPolicy)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = translator.SetConvertCharsetPolicy(convertCharsetPolicy, cfg.ConvertCharset); err != nil {
+		return errors.Annotate(err, "invalid convert-charset")
+	}
+	translator.SetStripAutoIDCache(cfg.StripAutoIDCache)
+	translator.SetStripStatsOptions(cfg.StripStatsOptions)
+	translator.SetStrictColumnIDs(cfg.StrictColumnIDs)
+	translator.SetEnumByName(cfg.EnumByName)
+
+	ignoreColumns := make(map[string][]string, len(cfg.IgnoreColumns))
+	for _, rule := range cfg.IgnoreColumns {
+		table := rule.Schema + "." + rule.Table
+		ignoreColumns[table] = append(ignoreColumns[table], rule.Columns...)
+	}
+	translator.SetIgnoreColumns(ignoreColumns)
+
+	if len(cfg.DeadLetterFile) > 0 {
+		translator.SetDeadLetter(newDeadLetterFile(cfg.DeadLetterFile))
+	} else {
+		translator.SetDeadLetter(nil)
+	}
+
+	return nil
+}
+
+type deadLetterRow struct {
+	Schema string `json:"schema"`
+	Table  string `json:"table"`
+	// the encoded row of the binlog, in base64
+	Row   []byte `json:"row"`
+	Error string `json:"error"`
+}
+
+// newDeadLetterFile returns the dead letter appending every row failed to be
+// translated to the file as a line of JSON, so it can be inspected and repaired later.
+func newDeadLetterFile(path string) translator.DeadLetterFunc {
+	return func(schema, table string, row []byte, cause error) {
+		log.Warn("drop the row failed to be translated", zap.String("schema", schema), zap.String("table", table), zap.String("file", path), zap.NamedError("cause", cause))
+
+		err := appendDeadLetter(path, &deadLetterRow{Schema: schema, Table: table, Row: row, Error: cause.Error()})
+		if err != nil {
+			log.Error("failed to write the dead letter", zap.String("schema", schema), zap.String("table", table), zap.Binary("row", row), zap.Error(err))
+		}
+	}
+}
+
+func appendDeadLetter(path string, row *deadLetterRow) error {
+	line, err := json.Marshal(row)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if _, err = f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return errors.Trace(err)
+	}
+	return errors.Trace(f.Close())
+}
+
+func parseZeroDatePolicy(policy string) (translator.ZeroDatePolicy, error) {
+	switch policy {
+	case "", "keep":
+		return translator.ZeroDateKeep, nil
+	case "null":
+		return translator.ZeroDateToNull, nil
+	case "min":
+		return translator.ZeroDateToMin, nil
+	default:
+		return 0, errors.Errorf("unknown zero-date-policy: %s", policy)
+	}
+}
+
+func parseConvertCharsetPolicy(policy string) (translator.ConvertCharsetPolicy, error) {
+	switch policy {
+	case "", "pass":
+		return translator.ConvertCharsetPass, nil
+	case "strip":
+		return translator.ConvertCharsetStrip, nil
+	case "rewrite":
+		return translator.ConvertCharsetRewrite, nil
+	default:
+		return 0, errors.Errorf("unknown convert-charset-policy: %s", policy)
+	}
+}
+
+func genRouterAndBinlogEvent(cfg *SyncerConfig) (*translator.TableRouter, *bf.BinlogEvent, error) {
 	var (
 		routeRules  []*router.TableRule
 		filterRules []*bf.BinlogEventRule
@@ -263,13 +379,13 @@ func genRouterAndBinlogEvent(cfg *SyncerConfig) (*router.Table, *bf.BinlogEvent,
 		doTables[j] = &baf.Table{Schema: rule.Source.Schema, Name: rule.Source.Table}
 	}
 	var (
-		tableRouter  *router.Table
+		tableRouter  *translator.TableRouter
 		binlogFilter *bf.BinlogEvent
 		err          error
 	)
 
-	if len(routeRules) > 0 && cfg.DestDBType == "oracle" {
-		tableRouter, err = router.NewTableRouter(cfg.CaseSensitive, routeRules)
+	if len(routeRules) > 0 && (cfg.DestDBType == "oracle" || cfg.DestDBType == "mysql" || cfg.DestDBType == "tidb") {
+		tableRouter, err = translator.NewTableRouter(cfg.CaseSensitive, routeRules)
 		if err != nil {
 			return nil, nil, errors.Annotate(err, "generate table router error")
 		}
@@ -14,7 +14,12 @@
 package drainer
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 
 	. "github.com/pingcap/check"
 	bf "github.com/pingcap/tidb-tools/pkg/binlog-filter"
@@ -116,3 +121,42 @@ func (t *taskGroupSuite) TestCombineFilterRules(c *C) {
 		c.Assert(expectFilter.SQLPattern, DeepEquals, filterRule.SQLPattern)
 	}
 }
+
+func (t *taskGroupSuite) TestSetTranslatorOptions(c *C) {
+	defer func() {
+		c.Assert(setTranslatorOptions(&SyncerConfig{}), IsNil)
+	}()
+
+	c.Assert(setTranslatorOptions(&SyncerConfig{}), IsNil)
+	c.Assert(setTranslatorOptions(&SyncerConfig{ZeroDatePolicy: "min", ZeroDateMin: "1970-01-01"}), IsNil)
+	c.Assert(setTranslatorOptions(&SyncerConfig{TimeZone: "UTC"}), IsNil)
+	c.Assert(setTranslatorOptions(&SyncerConfig{ConvertCharsetPolicy: "rewrite", ConvertCharset: "utf8mb4"}), IsNil)
+
+	invalid := []*SyncerConfig{
+		{ZeroDatePolicy: "zero"},
+		{ZeroDatePolicy: "min"},
+		{ZeroDatePolicy: "null", ZeroDateMin: "0000-00-00"},
+		{TimeZone: "Mars/Olympus"},
+		{ConvertCharsetPolicy: "drop"},
+		{ConvertCharsetPolicy: "rewrite"},
+	}
+	for _, cfg := range invalid {
+		c.Assert(setTranslatorOptions(cfg), NotNil, Commentf("config: %+v", cfg))
+	}
+}
+
+func (t *taskGroupSuite) TestDeadLetterFile(c *C) {
+	path := filepath.Join(c.MkDir(), "dead_letter.json")
+	deadLetter := newDeadLetterFile(path)
+	deadLetter("test", "t1", []byte{1, 2}, errors.New("gen insert fail"))
+	deadLetter("test", "t2", []byte{3}, errors.New("gen update fail"))
+
+	data, err := os.ReadFile(path)
+	c.Assert(err, IsNil)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	c.Assert(lines, HasLen, 2)
+
+	var row deadLetterRow
+	c.Assert(json.Unmarshal([]byte(lines[1]), &row), IsNil)
+	c.Assert(row, DeepEquals, deadLetterRow{Schema: "test", Table: "t2", Row: []byte{3}, Error: "gen update fail"})
+}
@@ -36,7 +36,9 @@ import (
 var (
 	defaultBatchSize   = 128
 	defaultWorkerCount = 16
-	index              int64
+	// the max number of placeholders in a prepared statement of MySQL
+	defaultMaxPlaceholders = 65535
+	index                  int64
 )
 
 type executor struct {
@@ -46,13 +48,16 @@ type executor struct {
 	info              *loopbacksync.LoopBackSync
 	queryHistogramVec *prometheus.HistogramVec
 	refreshTableInfo  func(schema string, table string) (info *tableInfo, err error)
+	maxPlaceholders   int
+	txnSessionSQL     string
 }
 
 func newExecutor(db *gosql.DB) *executor {
 	exe := &executor{
-		db:          db,
-		batchSize:   defaultBatchSize,
-		workerCount: defaultWorkerCount,
+		db:              db,
+		batchSize:       defaultBatchSize,
+		workerCount:     defaultWorkerCount,
+		maxPlaceholders: defaultMaxPlaceholders,
 	}
 
 	return exe
@@ -68,6 +73,16 @@ func (e *executor) withBatchSize(batchSize int) *executor {
 	return e
 }
 
+func (e *executor) withMaxPlaceholders(maxPlaceholders int) *executor {
+	e.maxPlaceholders = maxPlaceholders
+	return e
+}
+
+func (e *executor) withTxnSessionSQL(sql string) *executor {
+	e.txnSessionSQL = sql
+	return e
+}
+
 func (e *executor) setSyncInfo(info *loopbacksync.LoopBackSync) {
 	e.info = info
 }
@@ -144,6 +159,12 @@ func (e *executor) begin() (*tx, error) {
 		queryHistogramVec: e.queryHistogramVec,
 	}
 
+	if len(e.txnSessionSQL) > 0 {
+		if _, err = tx.autoRollbackExec(e.txnSessionSQL); err != nil {
+			return nil, errors.Annotate(err, "failed to set session")
+		}
+	}
+
 	if e.info != nil && e.info.LoopbackControl {
 		start := time.Now()
 
@@ -173,6 +194,9 @@ func (e *executor) bulkDelete(deletes []*DML) error {
 	argss := make([]interface{}, 0, len(deletes))
 
 	for _, dml := range deletes {
+		if err := dml.checkWhere(); err != nil {
+			return errors.Trace(err)
+		}
 		sql, args := dml.sql()
 		sqls.WriteString(sql)
 		sqls.WriteByte(';')
@@ -198,23 +222,32 @@ func (e *executor) bulkReplace(inserts []*DML) error {
 	}
 
 	info := inserts[0].info
+	columns := info.orderColumns(info.columns)
 
 	var builder strings.Builder
 
-	cols := "(" + buildColumnList(info.columns) + ")"
-	builder.WriteString("REPLACE INTO " + inserts[0].TableName() + cols + " VALUES ")
+	cols := "(" + buildColumnList(columns) + ")"
+	if info.referenced {
+		// see DML.replaceSQL
+		builder.WriteString("INSERT INTO " + inserts[0].TableName() + cols + " VALUES ")
+	} else {
+		builder.WriteString("REPLACE INTO " + inserts[0].TableName() + cols + " VALUES ")
+	}
 
-	holder := fmt.Sprintf("(%s)", holderString(len(info.columns)))
+	holder := fmt.Sprintf("(%s)", holderString(len(columns)))
 	for i := 0; i < len(inserts); i++ {
 		if i > 0 {
 			builder.WriteByte(',')
 		}
 		builder.WriteString(holder)
 	}
+	if info.referenced {
+		builder.WriteString(" ON DUPLICATE KEY UPDATE " + buildOnDuplicateList(columns))
+	}
 
-	args := make([]interface{}, 0, len(inserts)*len(info.columns))
+	args := make([]interface{}, 0, len(inserts)*len(columns))
 	for _, insert := range inserts {
-		for _, name := range info.columns {
+		for _, name := range columns {
 			v := insert.Values[name]
 			args = append(args, v)
 		}
@@ -250,19 +283,19 @@ func (e *executor) execTableBatch(ctx context.Context, dmls []*DML) error {
 	log.Debug("merge dmls", zap.Reflect("dmls", dmls), zap.Reflect("merged", types))
 
 	if allDeletes, ok := types[DeleteDMLType]; ok {
-		if err := e.splitExecDML(ctx, allDeletes, e.bulkDelete); err != nil {
+		if err := e.splitExecDML(ctx, allDeletes, e.batchSize, e.bulkDelete); err != nil {
 			return errors.Trace(err)
 		}
 	}
 
 	if allInserts, ok := types[InsertDMLType]; ok {
-		if err := e.splitExecDML(ctx, allInserts, e.bulkReplace); err != nil {
+		if err := e.splitExecDML(ctx, allInserts, e.replaceBatchSize(allInserts[0].info), e.bulkReplace); err != nil {
 			return errors.Trace(err)
 		}
 	}
 
 	if allUpdates, ok := types[UpdateDMLType]; ok {
-		if err := e.splitExecDML(ctx, allUpdates, e.bulkReplace); err != nil {
+		if err := e.splitExecDML(ctx, allUpdates, e.replaceBatchSize(allUpdates[0].info), e.bulkReplace); err != nil {
 			return errors.Trace(err)
 		}
 	}
@@ -270,11 +303,26 @@ func (e *executor) execTableBatch(ctx context.Context, dmls []*DML) error {
 	return nil
 }
 
-// splitExecDML split dmls to size of e.batchSize and call exec concurrently
-func (e *executor) splitExecDML(ctx context.Context, dmls []*DML, exec func(dmls []*DML) error) error {
+// replaceBatchSize returns the number of rows in a bulk replace of the table,
+// it's limited by e.maxPlaceholders since every column of a row takes a placeholder.
+func (e *executor) replaceBatchSize(info *tableInfo) int {
+	size := e.batchSize
+	if e.maxPlaceholders > 0 && len(info.columns) > 0 {
+		if limit := e.maxPlaceholders / len(info.columns); limit < size {
+			size = limit
+		}
+	}
+	if size < 1 {
+		size = 1
+	}
+	return size
+}
+
+// splitExecDML split dmls to size of batchSize and call exec concurrently
+func (e *executor) splitExecDML(ctx context.Context, dmls []*DML, batchSize int, exec func(dmls []*DML) error) error {
 	errg, _ := errgroup.WithContext(ctx)
 
-	for _, split := range splitDMLs(dmls, e.batchSize) {
+	for _, split := range splitDMLs(dmls, batchSize) {
 		split := split
 		errg.Go(func() error {
 			err := exec(split)
@@ -346,13 +394,38 @@ func (e *executor) singleExecRetry(ctx context.Context, allDMLs []*DML, safeMode
 }
 
 func (e *executor) singleExec(dmls []*DML, safeMode bool) error {
+	for _, dml := range dmls {
+		if err := dml.checkWhere(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
 	tx, err := e.begin()
 	if err != nil {
 		return errors.Trace(err)
 	}
 
 	for _, dml := range dmls {
-		if safeMode && dml.Tp == UpdateDMLType {
+		if safeMode && dml.Tp == UpdateDMLType && dml.info.referenced {
+			// deleting the old row of a referenced table cascades to the child rows,
+			// update it in place and only upsert the new one if it's not there.
+			sql, args := dml.updateSQL()
+			res, err := tx.autoRollbackExec(sql, args...)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			affected, err := res.RowsAffected()
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if affected == 0 {
+				sql, args = dml.replaceSQL()
+				_, err = tx.autoRollbackExec(sql, args...)
+				if err != nil {
+					return errors.Trace(err)
+				}
+			}
+		} else if safeMode && dml.Tp == UpdateDMLType {
 			sql, args := dml.deleteSQL()
 			_, err := tx.autoRollbackExec(sql, args...)
 			if err != nil {
@@ -16,6 +16,7 @@ package loader
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"regexp"
 	"sync/atomic"
@@ -66,7 +67,7 @@ func (s *executorSuite) TestSplitExecDML(c *C) {
 
 	var counter int32
 
-	err = e.splitExecDML(context.Background(), dmls, func(group []*DML) error {
+	err = e.splitExecDML(context.Background(), dmls, e.batchSize, func(group []*DML) error {
 		atomic.AddInt32(&counter, 1)
 		if len(group) < 2 {
 			return errors.New("fake")
@@ -77,6 +78,54 @@ func (s *executorSuite) TestSplitExecDML(c *C) {
 	c.Assert(counter, Equals, int32(3))
 }
 
+func (s *executorSuite) TestReplaceRespectsMaxPlaceholders(c *C) {
+	columns := make([]string, 0, 100)
+	for i := 0; i < 100; i++ {
+		columns = append(columns, fmt.Sprintf("c%02d", i))
+	}
+	info := &tableInfo{
+		columns:    columns,
+		uniqueKeys: []indexInfo{{"PRIMARY", []string{"c00"}}},
+	}
+	info.primaryKey = &info.uniqueKeys[0]
+
+	var dmls []*DML
+	for i := 0; i < 25; i++ {
+		values := make(map[string]interface{}, len(columns))
+		for _, col := range columns {
+			values[col] = i
+		}
+		dmls = append(dmls, &DML{
+			Database: "test",
+			Table:    "wide",
+			Tp:       InsertDMLType,
+			Values:   values,
+			info:     info,
+		})
+	}
+
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+	e := newExecutor(db).withBatchSize(20).withMaxPlaceholders(1000)
+	c.Assert(e.replaceBatchSize(info), Equals, 10)
+
+	// 25 rows are replaced by 3 statements with 10, 10 and 5 rows
+	mock.MatchExpectationsInOrder(false)
+	for _, rows := range []int{10, 10, 5} {
+		args := make([]driver.Value, rows*len(columns))
+		for i := range args {
+			args[i] = sqlmock.AnyArg()
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec("REPLACE INTO `test`.`wide`").WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, int64(rows)))
+		mock.ExpectCommit()
+	}
+
+	err = e.execTableBatch(context.Background(), dmls)
+	c.Assert(err, IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
 func (s *executorSuite) TestTryRefreshTableErr(c *C) {
 	tests := []struct {
 		err error
@@ -118,6 +167,66 @@ func (s *singleExecSuite) TestFailedToBeginTx(c *C) {
 	c.Assert(s.dbMock.ExpectationsWereMet(), IsNil)
 }
 
+func (s *singleExecSuite) TestDeleteByBinaryKey(c *C) {
+	key := []byte{'a', 0x00, 0xff, 0x00}
+	dml := DML{
+		Database: "unicorn",
+		Table:    "users",
+		Tp:       DeleteDMLType,
+		Values: map[string]interface{}{
+			"id":   key,
+			"name": "tester",
+		},
+		info: &tableInfo{
+			columns:    []string{"id", "name"},
+			uniqueKeys: []indexInfo{{"PRIMARY", []string{"id"}}},
+		},
+	}
+
+	// the binary key is passed as []byte as it is, so the driver sends it
+	// as binary data and the NUL bytes are matched exactly.
+	s.dbMock.ExpectBegin()
+	s.dbMock.ExpectExec(regexp.QuoteMeta("DELETE FROM `unicorn`.`users` WHERE `id` = ? LIMIT 1")).
+		WithArgs(key).WillReturnResult(sqlmock.NewResult(0, 1))
+	s.dbMock.ExpectCommit()
+
+	e := newExecutor(s.db)
+	err := e.singleExec([]*DML{&dml}, false)
+	c.Assert(err, IsNil)
+	c.Assert(s.dbMock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *singleExecSuite) TestTxnSessionSQL(c *C) {
+	var dmls []*DML
+	for i := 0; i < 3; i++ {
+		dmls = append(dmls, &DML{
+			Database: "unicorn",
+			Table:    "users",
+			Tp:       InsertDMLType,
+			Values: map[string]interface{}{
+				"id": i,
+			},
+			info: &tableInfo{
+				columns: []string{"id"},
+			},
+		})
+	}
+
+	sessionSQL := "SET @@session.tidb_replica_read = 'leader'"
+	s.dbMock.ExpectBegin()
+	s.dbMock.ExpectExec(regexp.QuoteMeta(sessionSQL)).WillReturnResult(sqlmock.NewResult(0, 0))
+	for i := 0; i < 3; i++ {
+		s.dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO `unicorn`.`users`(`id`) VALUES(?)")).
+			WithArgs(i).WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+	s.dbMock.ExpectCommit()
+
+	e := newExecutor(s.db).withTxnSessionSQL(sessionSQL)
+	err := e.singleExec(dmls, false)
+	c.Assert(err, IsNil)
+	c.Assert(s.dbMock.ExpectationsWereMet(), IsNil)
+}
+
 func (s *singleExecSuite) TestInsert(c *C) {
 	dml := DML{
 		Database: "unicorn",
@@ -219,6 +328,111 @@ func (s *singleExecSuite) TestSafeUpdate(c *C) {
 	c.Assert(s.dbMock.ExpectationsWereMet(), IsNil)
 }
 
+func (s *singleExecSuite) TestSafeUpdateChangingKey(c *C) {
+	dml := DML{
+		Database: "unicorn",
+		Table:    "users",
+		Tp:       UpdateDMLType,
+		OldValues: map[string]interface{}{
+			"id":  1,
+			"age": 1999,
+		},
+		Values: map[string]interface{}{
+			"id":  2,
+			"age": 2019,
+		},
+		info: &tableInfo{
+			columns:    []string{"id", "age"},
+			primaryKey: &indexInfo{name: "PRIMARY", columns: []string{"id"}},
+			uniqueKeys: []indexInfo{
+				{name: "PRIMARY", columns: []string{"id"}},
+			},
+		},
+	}
+
+	// delete by the old key and replace with the new row,
+	// so it's fine to apply the update again.
+	s.dbMock.ExpectBegin()
+	s.dbMock.ExpectExec(regexp.QuoteMeta("DELETE FROM `unicorn`.`users` WHERE `id` = ? LIMIT 1")).
+		WithArgs(1).WillReturnResult(sqlmock.NewResult(1, 1))
+	s.dbMock.ExpectExec(regexp.QuoteMeta("REPLACE INTO `unicorn`.`users`(`age`,`id`) VALUES(?,?)")).
+		WithArgs(2019, 2).WillReturnResult(sqlmock.NewResult(1, 1))
+	s.dbMock.ExpectCommit()
+
+	e := newExecutor(s.db)
+	err := e.singleExec([]*DML{&dml}, true)
+	c.Assert(err, IsNil)
+	c.Assert(s.dbMock.ExpectationsWereMet(), IsNil)
+
+	s.resetMock(c)
+
+	// a plain update without safe mode
+	s.dbMock.ExpectBegin()
+	s.dbMock.ExpectExec(regexp.QuoteMeta("UPDATE `unicorn`.`users` SET `age` = ?,`id` = ? WHERE `id` = ? LIMIT 1")).
+		WithArgs(2019, 2, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	s.dbMock.ExpectCommit()
+
+	e = newExecutor(s.db)
+	err = e.singleExec([]*DML{&dml}, false)
+	c.Assert(err, IsNil)
+	c.Assert(s.dbMock.ExpectationsWereMet(), IsNil)
+}
+
+func (s *singleExecSuite) TestSafeUpdateReferencedTable(c *C) {
+	dml := DML{
+		Database: "unicorn",
+		Table:    "users",
+		Tp:       UpdateDMLType,
+		OldValues: map[string]interface{}{
+			"id":  1,
+			"age": 1999,
+		},
+		Values: map[string]interface{}{
+			"id":  1,
+			"age": 2019,
+		},
+		info: &tableInfo{
+			columns:    []string{"id", "age"},
+			primaryKey: &indexInfo{name: "PRIMARY", columns: []string{"id"}},
+			uniqueKeys: []indexInfo{
+				{name: "PRIMARY", columns: []string{"id"}},
+			},
+			referenced: true,
+		},
+	}
+	updateSQL := "UPDATE `unicorn`.`users` SET `age` = ?,`id` = ? WHERE `id` = ? LIMIT 1"
+	upsertSQL := "INSERT INTO `unicorn`.`users`(`age`,`id`) VALUES(?,?) ON DUPLICATE KEY UPDATE `age`=VALUES(`age`),`id`=VALUES(`id`)"
+
+	// the old row is never deleted, even if the primary key is changed
+	for _, id := range []int{1, 2} {
+		dml.Values["id"] = id
+		s.dbMock.ExpectBegin()
+		s.dbMock.ExpectExec(regexp.QuoteMeta(updateSQL)).
+			WithArgs(2019, id, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		s.dbMock.ExpectCommit()
+
+		e := newExecutor(s.db)
+		err := e.singleExec([]*DML{&dml}, true)
+		c.Assert(err, IsNil)
+		c.Assert(s.dbMock.ExpectationsWereMet(), IsNil)
+
+		s.resetMock(c)
+	}
+
+	// the new row is upserted if the old one is not there
+	s.dbMock.ExpectBegin()
+	s.dbMock.ExpectExec(regexp.QuoteMeta(updateSQL)).
+		WithArgs(2019, 2, 1).WillReturnResult(sqlmock.NewResult(0, 0))
+	s.dbMock.ExpectExec(regexp.QuoteMeta(upsertSQL)).
+		WithArgs(2019, 2).WillReturnResult(sqlmock.NewResult(1, 1))
+	s.dbMock.ExpectCommit()
+
+	e := newExecutor(s.db)
+	err := e.singleExec([]*DML{&dml}, true)
+	c.Assert(err, IsNil)
+	c.Assert(s.dbMock.ExpectationsWereMet(), IsNil)
+}
+
 type bulkDelSuite struct{}
 
 var _ = Suite(&bulkDelSuite{})
@@ -232,6 +446,27 @@ func (s *bulkDelSuite) TestCanHandleEmptySlice(c *C) {
 	c.Assert(err, IsNil)
 }
 
+func (s *bulkDelSuite) TestRejectEmptyWhere(c *C) {
+	dml := DML{
+		Database: "unicorn",
+		Table:    "users",
+		Tp:       DeleteDMLType,
+		Values:   map[string]interface{}{},
+		info:     &tableInfo{},
+	}
+
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+
+	// never begin a transaction to run `DELETE FROM ... WHERE  LIMIT 1`
+	e := newExecutor(db)
+	err = e.bulkDelete([]*DML{&dml})
+	c.Assert(err, ErrorMatches, "no column to match the row of `unicorn`.`users`.*")
+	err = e.singleExec([]*DML{&dml}, false)
+	c.Assert(err, ErrorMatches, "no column to match the row of `unicorn`.`users`.*")
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
+
 func (s *bulkDelSuite) TestDeleteInBulk(c *C) {
 	var dmls []*DML
 	for i := 0; i < 3; i++ {
@@ -313,3 +548,39 @@ func (s *bulkReplaceSuite) TestReplaceInBulk(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(mock.ExpectationsWereMet(), IsNil)
 }
+
+func (s *bulkReplaceSuite) TestReplaceReferencedTableInBulk(c *C) {
+	info := &tableInfo{
+		columns:    []string{"a", "b"},
+		referenced: true,
+	}
+	var dmls []*DML
+	for i := 0; i < 2; i++ {
+		dml := DML{
+			Database: "d",
+			Table:    "t",
+			Tp:       InsertDMLType,
+			Values: map[string]interface{}{
+				"a": fmt.Sprintf("a_%d", i),
+				"b": fmt.Sprintf("b_%d", i),
+			},
+			info: info,
+		}
+		dmls = append(dmls, &dml)
+	}
+
+	db, mock, err := sqlmock.New()
+	c.Assert(err, IsNil)
+
+	mock.ExpectBegin()
+	sql := "INSERT INTO `d`.`t`(`a`,`b`) VALUES (?,?),(?,?) ON DUPLICATE KEY UPDATE `a`=VALUES(`a`),`b`=VALUES(`b`)"
+	mock.ExpectExec(regexp.QuoteMeta(sql)).
+		WithArgs("a_0", "b_0", "a_1", "b_1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	e := newExecutor(db)
+	err = e.bulkReplace(dmls)
+	c.Assert(err, IsNil)
+	c.Assert(mock.ExpectationsWereMet(), IsNil)
+}
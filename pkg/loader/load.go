@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"math"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -118,6 +119,36 @@ const (
 	SyncPartialColumn
 )
 
+// LargeValuePolicy decides how to handle the DML having a value larger than the threshold.
+type LargeValuePolicy int
+
+// LargeValuePolicy values.
+const (
+	// LargeValueSingle executes the DML alone instead of in a multi-value batch.
+	LargeValueSingle LargeValuePolicy = iota
+	// LargeValueReject fails the DML.
+	LargeValueReject
+)
+
+// DDLKind is a kind of DDL skipped by the loader unless it's allowed explicitly.
+type DDLKind string
+
+// DDLKind values.
+const (
+	// DDLKindPrivilege is the user, role and privilege statements like CREATE ROLE, GRANT and SET DEFAULT ROLE.
+	DDLKindPrivilege DDLKind = "privilege"
+	// DDLKindImport is the statements importing data from files like LOAD DATA and IMPORT INTO.
+	DDLKindImport DDLKind = "import"
+	// DDLKindAdmin is the operational statements like SPLIT TABLE, ADMIN, FLUSH and RESET.
+	DDLKindAdmin DDLKind = "admin"
+	// DDLKindLock is the session level LOCK TABLES and UNLOCK TABLES statements.
+	DDLKindLock DDLKind = "lock"
+	// DDLKindRebuild is the ALTER TABLE ... FORCE statements only rebuilding the table without any schema change.
+	DDLKindRebuild DDLKind = "rebuild"
+	// DDLKindRead is the read-only statements like SHOW CREATE TABLE and EXPLAIN.
+	DDLKindRead DDLKind = "read"
+)
+
 type options struct {
 	workerCount      int
 	batchSize        int
@@ -128,6 +159,18 @@ type options struct {
 	enableDispatch   bool
 	enableCausality  bool
 	merge            bool
+	largeValueSize   int
+	largeValuePolicy LargeValuePolicy
+	allowDDLKinds    map[DDLKind]struct{}
+	nullSafeEqual    bool
+	useIndexes       map[string]string
+	ddlMarkerSchema  string
+	ddlMarkerTable   string
+	pkFirst          bool
+	beforeImage      bool
+	maxPlaceholders  int
+	txnSessionSQL    string
+	fkReferenced     bool
 }
 
 var defaultLoaderOptions = options{
@@ -140,6 +183,18 @@ var defaultLoaderOptions = options{
 	enableDispatch:   true,
 	enableCausality:  true,
 	merge:            false,
+	largeValueSize:   0,
+	largeValuePolicy: LargeValueSingle,
+	allowDDLKinds:    nil,
+	nullSafeEqual:    false,
+	useIndexes:       nil,
+	ddlMarkerSchema:  "",
+	ddlMarkerTable:   "",
+	pkFirst:          false,
+	beforeImage:      false,
+	maxPlaceholders:  defaultMaxPlaceholders,
+	txnSessionSQL:    "",
+	fkReferenced:     false,
 }
 
 // A Option sets options such batch size, worker count etc.
@@ -190,6 +245,101 @@ func Merge(v bool) Option {
 	}
 }
 
+// LargeValue set the size threshold in bytes of a single TEXT/BLOB value,
+// and how to handle the DML having a value exceeding it, so a large row
+// won't blow past the packet limit in the middle of a batch.
+// The threshold is global, it applies to every column of every table.
+// 0 means no limit.
+func LargeValue(size int, policy LargeValuePolicy) Option {
+	return func(o *options) {
+		o.largeValueSize = size
+		o.largeValuePolicy = policy
+	}
+}
+
+// AllowDDLKinds set the kinds of DDL to execute, DDL of other skippable kinds
+// will be skipped, all of them are skipped by default.
+func AllowDDLKinds(kinds ...DDLKind) Option {
+	return func(o *options) {
+		o.allowDDLKinds = make(map[DDLKind]struct{}, len(kinds))
+		for _, kind := range kinds {
+			o.allowDDLKinds[kind] = struct{}{}
+		}
+	}
+}
+
+// NullSafeEqual set whether to match the key columns by the null-safe equal
+// operator `col <=> ?` instead of `col = ?` and `col IS NULL` in WHERE.
+// Only MySQL and TiDB support this operator.
+func NullSafeEqual(b bool) Option {
+	return func(o *options) {
+		o.nullSafeEqual = b
+	}
+}
+
+// UseIndex set the index to hint by USE INDEX in the update of the table,
+// in case the downstream optimizer picks a bad plan for the WHERE by key.
+// The delete is never hinted since MySQL rejects index hints in a single table DELETE.
+func UseIndex(schema string, table string, index string) Option {
+	return func(o *options) {
+		if o.useIndexes == nil {
+			o.useIndexes = make(map[string]string)
+		}
+		o.useIndexes[quoteSchema(schema, table)] = index
+	}
+}
+
+// DDLMarkerTable set the table to record every DDL executed downstream with its commit ts,
+// the table will be created if not exists. Empty table means not to record.
+func DDLMarkerTable(schema string, table string) Option {
+	return func(o *options) {
+		o.ddlMarkerSchema = schema
+		o.ddlMarkerTable = table
+	}
+}
+
+// PrimaryKeyFirst set whether to put the primary key columns first
+// in the column list of the generated insert and replace.
+func PrimaryKeyFirst(b bool) Option {
+	return func(o *options) {
+		o.pkFirst = b
+	}
+}
+
+// BeforeImageComment set whether to append a comment recording the before-image
+// key values to the update statements, it helps to debug the missed updates.
+func BeforeImageComment(b bool) Option {
+	return func(o *options) {
+		o.beforeImage = b
+	}
+}
+
+// MaxPlaceholders set the max number of placeholders in a statement,
+// a bulk replace will be split up so columns x rows doesn't exceed it.
+// 0 means no limit.
+func MaxPlaceholders(n int) Option {
+	return func(o *options) {
+		o.maxPlaceholders = n
+	}
+}
+
+// DetectReferencedTables set whether to look up in information_schema if a table
+// is referenced by foreign keys of other tables. Deleting a row of a referenced table
+// cascades to the child rows, so its updates are never split into delete and insert.
+func DetectReferencedTables(b bool) Option {
+	return func(o *options) {
+		o.fkReferenced = b
+	}
+}
+
+// TxnSessionSQL set the statement to execute at the beginning of every transaction,
+// like `SET @@session.tidb_replica_read = 'leader'` for a downstream routing by session.
+func TxnSessionSQL(sql string) Option {
+	return func(o *options) {
+		o.txnSessionSQL = sql
+	}
+}
+
 //SetloopBackSyncInfo set loop back sync info of loader
 func SetloopBackSyncInfo(loopBackSyncInfo *loopbacksync.LoopBackSync) Option {
 	return func(o *options) {
@@ -330,6 +480,15 @@ func (s *loaderImpl) refreshTableInfo(schema string, table string) (info *tableI
 	if err != nil {
 		return info, errors.Trace(err)
 	}
+	info.nullSafeEqual = s.opts.nullSafeEqual
+	info.useIndex = s.opts.useIndexes[quoteSchema(schema, table)]
+	info.pkFirst = s.opts.pkFirst
+	info.beforeImageComment = s.opts.beforeImage
+	if s.opts.fkReferenced {
+		if info.referenced, err = isReferencedByFK(s.db, schema, table); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
 
 	if len(info.uniqueKeys) == 0 {
 		log.Warn("table has no any primary key and unique index, it may be slow when syncing data to downstream, we highly recommend add primary key or unique key for table", zap.String("table", quoteSchema(schema, table)))
@@ -375,15 +534,21 @@ func needRefreshTableInfo(sql string) bool {
 	return true
 }
 
-func isCreateDatabaseDDL(sql string) bool {
+// needUseDatabase returns whether to use the database of the DDL before executing it,
+// the statements on a database itself must not use it, which may not exist or be being dropped.
+func needUseDatabase(sql string) bool {
 	stmt, err := parser.New().ParseOneStmt(sql, "", "")
 	if err != nil {
 		log.Error("parse sql failed", zap.String("sql", sql), zap.Error(err))
+		return true
+	}
+
+	switch stmt.(type) {
+	case *ast.CreateDatabaseStmt, *ast.DropDatabaseStmt:
 		return false
 	}
 
-	_, isCreateDatabase := stmt.(*ast.CreateDatabaseStmt)
-	return isCreateDatabase
+	return true
 }
 
 func (s *loaderImpl) execDDL(ddl *DDL) error {
@@ -392,13 +557,20 @@ func (s *loaderImpl) execDDL(ddl *DDL) error {
 		return nil
 	}
 
+	if kind, ok := skippableDDLKind(ddl.SQL); ok {
+		if _, allowed := s.opts.allowDDLKinds[kind]; !allowed {
+			log.Info("skip ddl", zap.String("sql", ddl.SQL), zap.String("kind", string(kind)))
+			return nil
+		}
+	}
+
 	err := util.RetryContext(s.ctx, maxDDLRetryCount, execDDLRetryWait, 1, func(context.Context) error {
 		tx, err := s.db.Begin()
 		if err != nil {
 			return err
 		}
 
-		if len(ddl.Database) > 0 && !isCreateDatabaseDDL(ddl.SQL) {
+		if len(ddl.Database) > 0 && needUseDatabase(ddl.SQL) {
 			_, err = tx.Exec(fmt.Sprintf("use %s;", quoteName(ddl.Database)))
 			if err != nil {
 				if rbErr := tx.Rollback(); rbErr != nil {
@@ -427,9 +599,45 @@ func (s *loaderImpl) execDDL(ddl *DDL) error {
 		return nil
 	}
 
+	if err == nil {
+		s.markDDL(ddl)
+	}
+
 	return errors.Trace(err)
 }
 
+func (s *loaderImpl) ddlMarkerEnabled() bool {
+	return len(s.opts.ddlMarkerSchema) > 0 && len(s.opts.ddlMarkerTable) > 0
+}
+
+func (s *loaderImpl) initDDLMarkerTable() error {
+	_, err := s.db.Exec(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", quoteName(s.opts.ddlMarkerSchema)))
+	if err != nil {
+		return errors.Annotate(err, "failed to create ddl marker db")
+	}
+
+	_, err = s.db.Exec(fmt.Sprintf(createDDLMarkerTableSQL, quoteSchema(s.opts.ddlMarkerSchema, s.opts.ddlMarkerTable)))
+	if err != nil {
+		return errors.Annotate(err, "failed to create ddl marker table")
+	}
+
+	return nil
+}
+
+// markDDL records the executed DDL into the marker table,
+// failing to record is only logged since the DDL is already applied.
+func (s *loaderImpl) markDDL(ddl *DDL) {
+	if !s.ddlMarkerEnabled() {
+		return
+	}
+
+	sql := fmt.Sprintf("INSERT INTO %s(commit_ts,db_name,table_name,ddl) VALUES(?,?,?,?)",
+		quoteSchema(s.opts.ddlMarkerSchema, s.opts.ddlMarkerTable))
+	if _, err := s.db.Exec(sql, ddl.CommitTS, ddl.Database, ddl.Table, ddl.SQL); err != nil {
+		log.Error("failed to record ddl into marker table", zap.String("sql", ddl.SQL), zap.Int64("commit ts", ddl.CommitTS), zap.Error(err))
+	}
+}
+
 func (s *loaderImpl) execByHash(executor *executor, byHash [][]*DML) error {
 	errg, _ := errgroup.WithContext(s.ctx)
 
@@ -526,6 +734,9 @@ func (s *loaderImpl) execDMLs(dmls []*DML) error {
 		if s.syncMode == SyncPartialColumn {
 			removeOrphanCols(dml.info, dml)
 		}
+		if s.opts.largeValuePolicy == LargeValueReject && s.hasLargeValue(dml) {
+			return errors.Errorf("%s has value larger than %d bytes", dml, s.opts.largeValueSize)
+		}
 	}
 
 	batchTables, singleDMLs := s.groupDMLs(dmls)
@@ -579,6 +790,12 @@ func (s *loaderImpl) Run() error {
 		}()
 	}
 
+	if s.ddlMarkerEnabled() {
+		if err := s.initDDLMarkerTable(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
 	txnManager := newTxnManager(100*1024 /* limit dml number */, s.input)
 	defer txnManager.Close()
 
@@ -636,9 +853,10 @@ func (s *loaderImpl) groupDMLs(dmls []*DML) (batchByTbls map[string][]*DML, sing
 		return
 	}
 	batchByTbls = make(map[string][]*DML)
+	isolated := s.isolateDMLs(dmls)
 	for _, dml := range dmls {
 		info := dml.info
-		if info.primaryKey != nil {
+		if _, ok := isolated[dml]; !ok && info.primaryKey != nil {
 			tblName := dml.TableName()
 			batchByTbls[tblName] = append(batchByTbls[tblName], dml)
 		} else {
@@ -648,6 +866,74 @@ func (s *loaderImpl) groupDMLs(dmls []*DML) (batchByTbls map[string][]*DML, sing
 	return
 }
 
+// isolateDMLs returns the DMLs that must be executed one by one along with all the DMLs
+// sharing a key with them, directly or not. The batches and the single DMLs are executed
+// concurrently, so the changes of a key must all go the same way to be executed in order.
+// The ones with large values are sent alone, and the batch executes the updates changing
+// the primary key of a referenced table as DELETE and INSERT, which cascades to the child rows.
+func (s *loaderImpl) isolateDMLs(dmls []*DML) map[*DML]struct{} {
+	isolated := make(map[*DML]struct{})
+	keys := make(map[string]struct{})
+	for _, dml := range dmls {
+		if s.hasLargeValue(dml) || changesReferencedKey(dml) {
+			isolated[dml] = struct{}{}
+			for _, key := range getKeys(dml) {
+				keys[key] = struct{}{}
+			}
+		}
+	}
+	if len(isolated) == 0 {
+		return isolated
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, dml := range dmls {
+			if _, ok := isolated[dml]; ok {
+				continue
+			}
+
+			dmlKeys := getKeys(dml)
+			for _, key := range dmlKeys {
+				if _, ok := keys[key]; ok {
+					isolated[dml] = struct{}{}
+					for _, key := range dmlKeys {
+						keys[key] = struct{}{}
+					}
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	return isolated
+}
+
+func changesReferencedKey(dml *DML) bool {
+	return dml.Tp == UpdateDMLType && dml.info.referenced && dml.info.primaryKey != nil && dml.updateKey()
+}
+
+func (s *loaderImpl) hasLargeValue(dml *DML) bool {
+	if s.opts.largeValueSize <= 0 {
+		return false
+	}
+
+	for _, v := range dml.Values {
+		var size int
+		switch v := v.(type) {
+		case string:
+			size = len(v)
+		case []byte:
+			size = len(v)
+		}
+		if size > s.opts.largeValueSize {
+			return true
+		}
+	}
+	return false
+}
+
 func countEvents(dmls []*DML) (insertEvent float64, deleteEvent float64, updateEvent float64) {
 	for _, dml := range dmls {
 		switch dml.Tp {
@@ -682,7 +968,8 @@ func filterGeneratedCols(dml *DML) {
 }
 
 func (s *loaderImpl) getExecutor() *executor {
-	e := newExecutor(s.db).withBatchSize(s.batchSize)
+	e := newExecutor(s.db).withBatchSize(s.batchSize).withMaxPlaceholders(s.opts.maxPlaceholders).
+		withTxnSessionSQL(s.opts.txnSessionSQL)
 	if s.syncMode == SyncPartialColumn {
 		e = e.withRefreshTableInfo(s.refreshTableInfo)
 	}
@@ -896,6 +1183,61 @@ func getAppliedTS(db *gosql.DB) int64 {
 	return appliedTS
 }
 
+// skippableDDLKind returns the kind of the DDL if it's a kind to skip by default.
+func skippableDDLKind(sql string) (DDLKind, bool) {
+	stmt, err := parser.New().ParseOneStmt(sql, "", "")
+	if err != nil {
+		// the parser doesn't support IMPORT INTO and RESET yet
+		fields := strings.Fields(sql)
+		if len(fields) >= 2 && strings.EqualFold(fields[0], "IMPORT") && strings.EqualFold(fields[1], "INTO") {
+			return DDLKindImport, true
+		}
+		if len(fields) >= 2 && strings.EqualFold(fields[0], "RESET") {
+			return DDLKindAdmin, true
+		}
+		log.Error("failed to parse", zap.Error(err), zap.String("sql", sql))
+		return "", false
+	}
+
+	switch n := stmt.(type) {
+	case *ast.AlterTableStmt:
+		if isRebuildTable(n) {
+			return DDLKindRebuild, true
+		}
+	case *ast.LoadDataStmt:
+		return DDLKindImport, true
+	case *ast.SplitRegionStmt, *ast.AdminStmt, *ast.FlushStmt:
+		return DDLKindAdmin, true
+	case *ast.LockTablesStmt, *ast.UnlockTablesStmt:
+		return DDLKindLock, true
+	case *ast.ShowStmt, *ast.ExplainStmt:
+		return DDLKindRead, true
+	case *ast.CreateUserStmt, *ast.AlterUserStmt, *ast.DropUserStmt, *ast.RenameUserStmt,
+		*ast.SetPwdStmt, *ast.GrantStmt, *ast.GrantRoleStmt, *ast.RevokeStmt, *ast.RevokeRoleStmt,
+		*ast.SetDefaultRoleStmt, *ast.SetRoleStmt:
+		return DDLKindPrivilege, true
+	}
+
+	return "", false
+}
+
+// isRebuildTable checks whether the statement is `ALTER TABLE ... FORCE`,
+// optionally with the ALGORITHM and LOCK clauses.
+func isRebuildTable(n *ast.AlterTableStmt) bool {
+	force := false
+	for _, spec := range n.Specs {
+		switch spec.Tp {
+		case ast.AlterTableForce:
+			force = true
+		case ast.AlterTableAlgorithm, ast.AlterTableLock:
+		default:
+			return false
+		}
+	}
+
+	return force
+}
+
 func isSetTiFlashReplica(sql string) bool {
 	stmt, err := parser.New().ParseOneStmt(sql, "", "")
 	if err != nil {
@@ -17,6 +17,7 @@ import (
 	"context"
 	"database/sql"
 	"reflect"
+	"regexp"
 	"sync"
 	"time"
 
@@ -297,6 +298,75 @@ func (s *groupDMLsSuite) TestGroupByTableName(c *check.C) {
 	c.Assert(single, check.HasLen, 2)
 }
 
+func (s *groupDMLsSuite) TestLargeValueOnlySingle(c *check.C) {
+	ld := loaderImpl{merge: true, opts: options{largeValueSize: 4}}
+	pk := indexInfo{name: "PRIMARY", columns: []string{"id"}}
+	info := tableInfo{columns: []string{"id", "a"}, primaryKey: &pk, uniqueKeys: []indexInfo{pk}}
+	dmls := []*DML{
+		{Table: "test1", Tp: InsertDMLType, info: &info, Values: map[string]interface{}{"id": 1, "a": "1234"}},
+		{Table: "test1", Tp: InsertDMLType, info: &info, Values: map[string]interface{}{"id": 2, "a": []byte("12345")}},
+		{Table: "test1", Tp: InsertDMLType, info: &info, Values: map[string]interface{}{"id": 3, "a": 123456}},
+	}
+	batch, single := ld.groupDMLs(dmls)
+	c.Assert(batch, check.HasLen, 1)
+	c.Assert(batch[dmls[0].TableName()], check.DeepEquals, []*DML{dmls[0], dmls[2]})
+	c.Assert(single, check.DeepEquals, []*DML{dmls[1]})
+}
+
+func (s *groupDMLsSuite) TestLargeValueKeepKeyOrder(c *check.C) {
+	ld := loaderImpl{merge: true, opts: options{largeValueSize: 4}}
+	pk := indexInfo{name: "PRIMARY", columns: []string{"id"}}
+	info := tableInfo{columns: []string{"id", "a"}, primaryKey: &pk, uniqueKeys: []indexInfo{pk}}
+	dmls := []*DML{
+		// changes id 1 to 2 before the large value is inserted with id 1
+		{Table: "test1", Tp: UpdateDMLType, info: &info,
+			Values:    map[string]interface{}{"id": 2, "a": "x"},
+			OldValues: map[string]interface{}{"id": 1, "a": "x"}},
+		{Table: "test1", Tp: InsertDMLType, info: &info, Values: map[string]interface{}{"id": 3, "a": "y"}},
+		{Table: "test1", Tp: InsertDMLType, info: &info, Values: map[string]interface{}{"id": 1, "a": "12345"}},
+		{Table: "test1", Tp: UpdateDMLType, info: &info,
+			Values:    map[string]interface{}{"id": 1, "a": "z"},
+			OldValues: map[string]interface{}{"id": 1, "a": "12345"}},
+		// shares id 2 with the first update only
+		{Table: "test1", Tp: DeleteDMLType, info: &info, Values: map[string]interface{}{"id": 2, "a": "x"}},
+	}
+	batch, single := ld.groupDMLs(dmls)
+	c.Assert(batch[dmls[0].TableName()], check.DeepEquals, []*DML{dmls[1]})
+	c.Assert(single, check.DeepEquals, []*DML{dmls[0], dmls[2], dmls[3], dmls[4]})
+}
+
+func (s *groupDMLsSuite) TestReferencedKeyChangeOnlySingle(c *check.C) {
+	ld := loaderImpl{merge: true}
+	pk := indexInfo{name: "PRIMARY", columns: []string{"id"}}
+	info := tableInfo{columns: []string{"id", "a"}, primaryKey: &pk, uniqueKeys: []indexInfo{pk}, referenced: true}
+	dmls := []*DML{
+		{Table: "test1", Tp: UpdateDMLType, info: &info,
+			Values:    map[string]interface{}{"id": 1, "a": "y"},
+			OldValues: map[string]interface{}{"id": 1, "a": "x"}},
+		// the batch would delete the old row and cascade to the child rows
+		{Table: "test1", Tp: UpdateDMLType, info: &info,
+			Values:    map[string]interface{}{"id": 3, "a": "x"},
+			OldValues: map[string]interface{}{"id": 2, "a": "x"}},
+	}
+	batch, single := ld.groupDMLs(dmls)
+	c.Assert(batch[dmls[0].TableName()], check.DeepEquals, []*DML{dmls[0]})
+	c.Assert(single, check.DeepEquals, []*DML{dmls[1]})
+}
+
+func (s *groupDMLsSuite) TestRejectLargeValue(c *check.C) {
+	ld := &loaderImpl{
+		opts: options{largeValueSize: 4, largeValuePolicy: LargeValueReject},
+		getTableInfoFromDB: func(*sql.DB, string, string) (*tableInfo, error) {
+			return &tableInfo{columns: []string{"a"}}, nil
+		},
+	}
+	dmls := []*DML{
+		{Database: "test", Table: "test1", Tp: InsertDMLType, Values: map[string]interface{}{"a": "12345"}},
+	}
+	err := ld.execDMLs(dmls)
+	c.Assert(err, check.ErrorMatches, ".*has value larger than 4 bytes.*")
+}
+
 type getTblInfoSuite struct{}
 
 var _ = check.Suite(&getTblInfoSuite{})
@@ -320,22 +390,115 @@ func (s *getTblInfoSuite) TestShouldCacheResult(c *check.C) {
 	c.Assert(nCalled, check.Equals, 1)
 }
 
-type isCreateDBDDLSuite struct{}
+func (s *getTblInfoSuite) TestDetectReferencedTables(c *check.C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, check.IsNil)
+	defer db.Close()
+
+	utilGetTableInfo := func(db *sql.DB, schema string, table string) (info *tableInfo, err error) {
+		return &tableInfo{columns: []string{"id", "name"}}, nil
+	}
 
-var _ = check.Suite(&isCreateDBDDLSuite{})
+	// information_schema is never queried by default
+	ld := loaderImpl{db: db, getTableInfoFromDB: utilGetTableInfo, opts: defaultLoaderOptions}
+	info, err := ld.getTableInfo("test", "contacts")
+	c.Assert(err, check.IsNil)
+	c.Assert(info.referenced, check.IsFalse)
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
 
-func (s *isCreateDBDDLSuite) TestInvalidSQL(c *check.C) {
-	c.Assert(isCreateDatabaseDDL("INSERT INTO Y a b c;"), check.IsFalse)
+	DetectReferencedTables(true)(&ld.opts)
+	mock.ExpectQuery(regexp.QuoteMeta(referencedSQL)).WithArgs("test", "contacts").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	info, err = ld.refreshTableInfo("test", "contacts")
+	c.Assert(err, check.IsNil)
+	c.Assert(info.referenced, check.IsTrue)
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
 }
 
-func (s *isCreateDBDDLSuite) TestNonCreateDBSQL(c *check.C) {
-	c.Assert(isCreateDatabaseDDL("SELECT 1;"), check.IsFalse)
-	c.Assert(isCreateDatabaseDDL(`INSERT INTO tbl(id, name) VALUES(1, "test";`), check.IsFalse)
+func (s *getTblInfoSuite) TestRefreshAfterPrimaryKeyChange(c *check.C) {
+	withPK := &tableInfo{
+		columns:    []string{"id", "name"},
+		uniqueKeys: []indexInfo{{"PRIMARY", []string{"id"}}},
+	}
+	withPK.primaryKey = &withPK.uniqueKeys[0]
+	withoutPK := &tableInfo{columns: []string{"id", "name"}}
+
+	downstream := withPK
+	ld := &loaderImpl{
+		merge:      true,
+		successTxn: make(chan *Txn, 1),
+		getTableInfoFromDB: func(*sql.DB, string, string) (*tableInfo, error) {
+			return downstream, nil
+		},
+	}
+	bm := newBatchManager(ld)
+	bm.fExecDDL = func(*DDL) error { return nil }
+
+	info, err := ld.getTableInfo("test", "contacts")
+	c.Assert(err, check.IsNil)
+	c.Assert(info.primaryKey, check.NotNil)
+
+	// the cached table info is evicted once the DDL is executed,
+	// so the following DMLs use the new keys.
+	downstream = withoutPK
+	err = bm.execDDL(NewDDLTxn("test", "contacts", "ALTER TABLE contacts DROP PRIMARY KEY"))
+	c.Assert(err, check.IsNil)
+	<-ld.successTxn
+
+	dml := &DML{
+		Database:  "test",
+		Table:     "contacts",
+		Tp:        UpdateDMLType,
+		OldValues: map[string]interface{}{"id": 1, "name": "a"},
+		Values:    map[string]interface{}{"id": 1, "name": "b"},
+	}
+	err = ld.setDMLInfo(dml)
+	c.Assert(err, check.IsNil)
+	c.Assert(dml.info.primaryKey, check.IsNil)
+
+	sql, args := dml.updateSQL()
+	c.Assert(sql, check.Equals, "UPDATE `test`.`contacts` SET `id` = ?,`name` = ? WHERE `id` = ? AND `name` = ? LIMIT 1")
+	c.Assert(args, check.DeepEquals, []interface{}{1, "b", 1, "a"})
+
+	// DMLs of the table without primary key can't be batched by key
+	batch, single := ld.groupDMLs([]*DML{dml})
+	c.Assert(batch, check.HasLen, 0)
+	c.Assert(single, check.DeepEquals, []*DML{dml})
+
+	// and the other way around when the primary key is added back
+	downstream = withPK
+	err = bm.execDDL(NewDDLTxn("test", "contacts", "ALTER TABLE contacts ADD PRIMARY KEY(id)"))
+	c.Assert(err, check.IsNil)
+	<-ld.successTxn
+
+	err = ld.setDMLInfo(dml)
+	c.Assert(err, check.IsNil)
+	c.Assert(dml.info.primaryKey, check.NotNil)
+	batch, single = ld.groupDMLs([]*DML{dml})
+	c.Assert(batch, check.HasLen, 1)
+	c.Assert(single, check.HasLen, 0)
 }
 
-func (s *isCreateDBDDLSuite) TestCreateDBSQL(c *check.C) {
-	c.Assert(isCreateDatabaseDDL("CREATE DATABASE test;"), check.IsTrue)
-	c.Assert(isCreateDatabaseDDL("create database `db2`;"), check.IsTrue)
+type needUseDatabaseSuite struct{}
+
+var _ = check.Suite(&needUseDatabaseSuite{})
+
+func (s *needUseDatabaseSuite) TestInvalidSQL(c *check.C) {
+	c.Assert(needUseDatabase("INSERT INTO Y a b c;"), check.IsTrue)
+}
+
+func (s *needUseDatabaseSuite) TestNonDatabaseSQL(c *check.C) {
+	c.Assert(needUseDatabase("SELECT 1;"), check.IsTrue)
+	c.Assert(needUseDatabase(`INSERT INTO tbl(id, name) VALUES(1, "test";`), check.IsTrue)
+	c.Assert(needUseDatabase("TRUNCATE TABLE t1"), check.IsTrue)
+	c.Assert(needUseDatabase("CREATE TABLE t1(id int)"), check.IsTrue)
+}
+
+func (s *needUseDatabaseSuite) TestDatabaseSQL(c *check.C) {
+	c.Assert(needUseDatabase("CREATE DATABASE test;"), check.IsFalse)
+	c.Assert(needUseDatabase("create database `db2`;"), check.IsFalse)
+	c.Assert(needUseDatabase("DROP DATABASE test;"), check.IsFalse)
+	c.Assert(needUseDatabase("drop database if exists `db2`;"), check.IsFalse)
 }
 
 type needRefreshTableInfoSuite struct{}
@@ -391,6 +554,116 @@ func (s *execDDLSuite) TestShouldUseDatabase(c *check.C) {
 	c.Assert(err, check.IsNil)
 }
 
+func (s *execDDLSuite) TestShouldNotUseDroppedDatabase(c *check.C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, check.IsNil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DROP DATABASE `test_db`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("use `test_db`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("TRUNCATE TABLE t1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	loader := &loaderImpl{db: db, ctx: context.Background()}
+
+	err = loader.execDDL(&DDL{SQL: "DROP DATABASE `test_db`", Database: "test_db"})
+	c.Assert(err, check.IsNil)
+	err = loader.execDDL(&DDL{SQL: "TRUNCATE TABLE t1", Database: "test_db", Table: "t1"})
+	c.Assert(err, check.IsNil)
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+}
+
+func (s *execDDLSuite) TestRecordDDLInMarkerTable(c *check.C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, check.IsNil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("use `test`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE t1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `retl`.`ddl_marker`(commit_ts,db_name,table_name,ddl) VALUES(?,?,?,?)")).
+		WithArgs(int64(42), "test", "t1", "CREATE TABLE t1(id int primary key)").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	loader := &loaderImpl{db: db, ctx: context.Background()}
+	DDLMarkerTable("retl", "ddl_marker")(&loader.opts)
+
+	ddl := DDL{SQL: "CREATE TABLE t1(id int primary key)", Database: "test", Table: "t1", CommitTS: 42}
+	err = loader.execDDL(&ddl)
+	c.Assert(err, check.IsNil)
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+}
+
+func (s *execDDLSuite) TestSkipDDLKindsByDefault(c *check.C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, check.IsNil)
+
+	loader := &loaderImpl{db: db, ctx: context.Background()}
+
+	tests := []struct {
+		sql  string
+		kind DDLKind
+	}{
+		{"CREATE ROLE r1", DDLKindPrivilege},
+		{"DROP ROLE r1", DDLKindPrivilege},
+		{"CREATE USER u1", DDLKindPrivilege},
+		{"GRANT SELECT ON test.* TO u1", DDLKindPrivilege},
+		{"GRANT r1 TO u1", DDLKindPrivilege},
+		{"REVOKE SELECT ON test.* FROM u1", DDLKindPrivilege},
+		{"SET DEFAULT ROLE r1 TO u1", DDLKindPrivilege},
+		{"LOAD DATA LOCAL INFILE '/tmp/t1.csv' INTO TABLE t1", DDLKindImport},
+		{"IMPORT INTO t1 FROM '/tmp/t1.csv'", DDLKindImport},
+		{"SPLIT TABLE t1 BETWEEN (0) AND (1000000) REGIONS 16", DDLKindAdmin},
+		{"SPLIT TABLE t1 INDEX idx BY (100), (200)", DDLKindAdmin},
+		{"ADMIN CHECK TABLE t1", DDLKindAdmin},
+		{"FLUSH TABLES", DDLKindAdmin},
+		{"FLUSH PRIVILEGES", DDLKindAdmin},
+		{"RESET MASTER", DDLKindAdmin},
+		{"LOCK TABLES t1 READ, t2 WRITE", DDLKindLock},
+		{"UNLOCK TABLES", DDLKindLock},
+		{"ALTER TABLE t1 FORCE", DDLKindRebuild},
+		{"ALTER TABLE test.t1 FORCE, ALGORITHM = INPLACE, LOCK = NONE", DDLKindRebuild},
+		{"SHOW CREATE TABLE t1", DDLKindRead},
+		{"SHOW TABLES", DDLKindRead},
+		{"EXPLAIN SELECT * FROM t1", DDLKindRead},
+		{"DESC t1", DDLKindRead},
+	}
+	for _, test := range tests {
+		kind, ok := skippableDDLKind(test.sql)
+		c.Assert(ok, check.IsTrue, check.Commentf("sql: %s", test.sql))
+		c.Assert(kind, check.Equals, test.kind, check.Commentf("sql: %s", test.sql))
+
+		err = loader.execDDL(&DDL{SQL: test.sql, Database: "test"})
+		c.Assert(err, check.IsNil)
+	}
+	// nothing is executed
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+
+	// the table is not only rebuilt
+	_, ok := skippableDDLKind("ALTER TABLE t1 ADD COLUMN a INT, FORCE")
+	c.Assert(ok, check.IsFalse)
+	_, ok = skippableDDLKind("ALTER TABLE t1 ALGORITHM = INPLACE")
+	c.Assert(ok, check.IsFalse)
+}
+
+func (s *execDDLSuite) TestExecAllowedPrivilegeDDL(c *check.C) {
+	db, mock, err := sqlmock.New()
+	c.Assert(err, check.IsNil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("GRANT SELECT").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	loader := &loaderImpl{db: db, ctx: context.Background()}
+	AllowDDLKinds(DDLKindPrivilege)(&loader.opts)
+
+	err = loader.execDDL(&DDL{SQL: "GRANT SELECT ON test.* TO u1"})
+	c.Assert(err, check.IsNil)
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
+}
+
 type batchManagerSuite struct{}
 
 var _ = check.Suite(&batchManagerSuite{})
@@ -148,6 +148,47 @@ func (m *modelSuite) TestMerge(c *check.C) {
 	}
 }
 
+func (m *modelSuite) TestMergeUpdatesOfSameKey(c *check.C) {
+	info := &tableInfo{
+		columns:    []string{"k", "v"},
+		uniqueKeys: []indexInfo{{"PRIMARY", []string{"k"}}},
+	}
+	info.primaryKey = &info.uniqueKeys[0]
+
+	update := func(oldv, v int) *DML {
+		return &DML{
+			Database:  "test",
+			Table:     "t",
+			Tp:        UpdateDMLType,
+			OldValues: map[string]interface{}{"k": 1, "v": oldv},
+			Values:    map[string]interface{}{"k": 1, "v": v},
+			info:      info,
+		}
+	}
+
+	res, err := mergeByPrimaryKey([]*DML{update(1, 2), update(2, 3), update(3, 4)})
+	c.Assert(err, check.IsNil)
+	c.Assert(res, check.HasLen, 1)
+	c.Assert(res[UpdateDMLType], check.HasLen, 1)
+	merged := res[UpdateDMLType][0]
+	c.Assert(merged.OldValues, check.DeepEquals, map[string]interface{}{"k": 1, "v": 1})
+	c.Assert(merged.Values, check.DeepEquals, map[string]interface{}{"k": 1, "v": 4})
+
+	sql, args := merged.sql()
+	c.Assert(sql, check.Equals, "UPDATE `test`.`t` SET `k` = ?,`v` = ? WHERE `k` = ? LIMIT 1")
+	c.Assert(args, check.DeepEquals, []interface{}{1, 4, 1})
+
+	// the delete and insert in between break the merge of updates,
+	// only the insert with the final values is left.
+	deleteDML := &DML{Tp: DeleteDMLType, Values: map[string]interface{}{"k": 1, "v": 2}, info: info}
+	insertDML := &DML{Tp: InsertDMLType, Values: map[string]interface{}{"k": 1, "v": 5}, info: info}
+	res, err = mergeByPrimaryKey([]*DML{update(1, 2), deleteDML, insertDML, update(5, 6)})
+	c.Assert(err, check.IsNil)
+	c.Assert(res, check.HasLen, 1)
+	c.Assert(res[InsertDMLType], check.HasLen, 1)
+	c.Assert(res[InsertDMLType][0].Values, check.DeepEquals, map[string]interface{}{"k": 1, "v": 6})
+}
+
 func logDMLs(dmls []*DML, c *check.C) {
 	c.Log("dmls: ", len(dmls))
 	for _, dml := range dmls {
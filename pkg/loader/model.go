@@ -14,11 +14,14 @@
 package loader
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
 	"go.uber.org/zap"
 )
@@ -55,6 +58,8 @@ type DDL struct {
 	// should skip to execute this DDL at downstream and just refresh the downstream table info.
 	// one case for this usage is for bidirectional replication and only execute DDL at one side.
 	ShouldSkip bool
+	// commit ts of the DDL in upstream, recorded in the DDL marker table if set.
+	CommitTS int64
 }
 
 // Txn holds transaction info, an DDL or DML sequences
@@ -160,6 +165,30 @@ func (dml *DML) oldPrimaryKeyValues() []interface{} {
 	return values
 }
 
+// Hash returns a digest of the database, table, type and values of the DML,
+// the same change always gets the same hash so it can be used to deduplicate
+// the DMLs applied more than once.
+func (dml *DML) Hash() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%q.%q:%d", dml.Database, dml.Table, dml.Tp)
+	writeValues := func(values map[string]interface{}) {
+		names := make([]string, 0, len(values))
+		for name := range values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(h, "|%d", len(names))
+		for _, name := range names {
+			// with the type so values like 1 and "1" differ
+			fmt.Fprintf(h, "|%q=%T:%q", name, values[name], fmt.Sprintf("%v", values[name]))
+		}
+	}
+	writeValues(dml.Values)
+	writeValues(dml.OldValues)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 // TableName returns the fully qualified name of the DML's table
 func (dml *DML) TableName() string {
 	return quoteSchema(dml.Database, dml.Table)
@@ -168,7 +197,7 @@ func (dml *DML) TableName() string {
 func (dml *DML) updateSQL() (sql string, args []interface{}) {
 	builder := new(strings.Builder)
 
-	fmt.Fprintf(builder, "UPDATE %s SET ", dml.TableName())
+	fmt.Fprintf(builder, "UPDATE %s%s SET ", dml.TableName(), dml.indexHint())
 
 	for _, name := range dml.columnNames() {
 		if len(args) > 0 {
@@ -185,17 +214,60 @@ func (dml *DML) updateSQL() (sql string, args []interface{}) {
 	args = append(args, whereArgs...)
 
 	builder.WriteString(" LIMIT 1")
+	if dml.info.beforeImageComment {
+		builder.WriteString(dml.beforeImageComment())
+	}
 	sql = builder.String()
 	return
 }
 
+// commentEscaper escapes the text in a comment so it can't terminate the comment early,
+// the `?` are escaped too because the driver interpolating params treats any `?` as placeholder.
+var commentEscaper = strings.NewReplacer("*/", `*\/`, "?", `\x3f`)
+
+// beforeImageComment returns the comment recording the key values used to find the old row.
+func (dml *DML) beforeImageComment() string {
+	names, values := dml.whereSlice()
+
+	builder := new(strings.Builder)
+	for i, name := range names {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(quoteName(name) + "=")
+		switch v := values[i].(type) {
+		case nil:
+			builder.WriteString("NULL")
+		case string:
+			builder.WriteString(strconv.Quote(v))
+		case []byte:
+			builder.WriteString(strconv.Quote(string(v)))
+		default:
+			fmt.Fprintf(builder, "%v", v)
+		}
+	}
+
+	return " /* before: " + commentEscaper.Replace(builder.String()) + " */"
+}
+
+func (dml *DML) indexHint() string {
+	if len(dml.info.useIndex) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(" USE INDEX (%s)", quoteName(dml.info.useIndex))
+}
+
 func (dml *DML) buildWhere(builder *strings.Builder) (args []interface{}) {
 	wnames, wargs := dml.whereSlice()
 	for i := 0; i < len(wnames); i++ {
 		if i > 0 {
 			builder.WriteString(" AND ")
 		}
-		if wargs[i] == nil {
+		if dml.info.nullSafeEqual {
+			builder.WriteString(quoteName(wnames[i]) + " <=> ?")
+			args = append(args, wargs[i])
+		} else if wargs[i] == nil {
 			builder.WriteString(quoteName(wnames[i]) + " IS NULL")
 		} else {
 			builder.WriteString(quoteName(wnames[i]) + " = ?")
@@ -239,6 +311,20 @@ func (dml *DML) whereSlice() (colNames []string, args []interface{}) {
 	return names, dml.whereValues(names)
 }
 
+// checkWhere makes sure the WHERE clause of the DML matches by some columns,
+// an empty one would never generate a valid statement to match the row.
+func (dml *DML) checkWhere() error {
+	if dml.Tp == InsertDMLType {
+		return nil
+	}
+
+	if names, _ := dml.whereSlice(); len(names) == 0 {
+		return errors.Errorf("no column to match the row of %s, dml: %v", dml.TableName(), dml)
+	}
+
+	return nil
+}
+
 func (dml *DML) deleteSQL() (sql string, args []interface{}) {
 	builder := new(strings.Builder)
 
@@ -262,8 +348,20 @@ func (dml *DML) columnNames() []string {
 }
 
 func (dml *DML) replaceSQL() (sql string, args []interface{}) {
-	names := dml.columnNames()
-	sql = fmt.Sprintf("REPLACE INTO %s(%s) VALUES(%s)", dml.TableName(), buildColumnList(names), holderString(len(names)))
+	// REPLACE deletes the old row first, which may cascade to the child rows
+	// if the table is referenced by foreign keys, so update the row instead.
+	if dml.info.referenced {
+		return dml.insertOnDuplicateSQL()
+	}
+
+	sql, args = dml.insertSQL()
+	sql = strings.Replace(sql, "INSERT", "REPLACE", 1)
+	return
+}
+
+func (dml *DML) insertSQL() (sql string, args []interface{}) {
+	names := dml.info.orderColumns(dml.columnNames())
+	sql = fmt.Sprintf("INSERT INTO %s(%s) VALUES(%s)", dml.TableName(), buildColumnList(names), holderString(len(names)))
 	for _, name := range names {
 		v := dml.Values[name]
 		args = append(args, v)
@@ -271,9 +369,9 @@ func (dml *DML) replaceSQL() (sql string, args []interface{}) {
 	return
 }
 
-func (dml *DML) insertSQL() (sql string, args []interface{}) {
-	sql, args = dml.replaceSQL()
-	sql = strings.Replace(sql, "REPLACE", "INSERT", 1)
+func (dml *DML) insertOnDuplicateSQL() (sql string, args []interface{}) {
+	sql, args = dml.insertSQL()
+	sql += " ON DUPLICATE KEY UPDATE " + buildOnDuplicateList(dml.columnNames())
 	return
 }
 
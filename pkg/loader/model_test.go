@@ -22,6 +22,7 @@ import (
 	"github.com/pingcap/tidb-binlog/drainer/loopbacksync"
 
 	"github.com/pingcap/check"
+	"github.com/pingcap/tidb/parser"
 )
 
 type dmlSuite struct {
@@ -102,6 +103,180 @@ func (d *dmlSuite) testWhere(c *check.C, tp DMLType) {
 	c.Assert(strings.Count(builder.String(), "?"), check.Equals, len(args))
 }
 
+func (d *dmlSuite) TestWhereCompositeKeyWithNull(c *check.C) {
+	dml := &DML{
+		Database: "test",
+		Table:    "test",
+		Tp:       DeleteDMLType,
+		Values: map[string]interface{}{
+			"a": 1,
+			"b": nil,
+			"c": "x",
+		},
+		info: &tableInfo{
+			columns:    []string{"a", "b", "c"},
+			uniqueKeys: []indexInfo{{"uk", []string{"a", "b"}}},
+		},
+	}
+
+	// a unique key with NULL member doesn't identify a row,
+	// fall back to all columns and keep args aligned with the placeholders.
+	sql, args := dml.deleteSQL()
+	c.Assert(sql, check.Equals, "DELETE FROM `test`.`test` WHERE `a` = ? AND `b` IS NULL AND `c` = ? LIMIT 1")
+	c.Assert(args, check.DeepEquals, []interface{}{1, "x"})
+
+	dml.info.nullSafeEqual = true
+	sql, args = dml.deleteSQL()
+	c.Assert(sql, check.Equals, "DELETE FROM `test`.`test` WHERE `a` <=> ? AND `b` <=> ? AND `c` <=> ? LIMIT 1")
+	c.Assert(args, check.DeepEquals, []interface{}{1, nil, "x"})
+}
+
+func (d *dmlSuite) TestNullSafeEqualWhere(c *check.C) {
+	dml := getDML(false, DeleteDMLType)
+	dml.Values = map[string]interface{}{
+		"id": 1,
+		"a1": nil,
+	}
+
+	builder := new(strings.Builder)
+	args := dml.buildWhere(builder)
+	c.Assert(builder.String(), check.Equals, "`a1` IS NULL AND `id` = ?")
+	c.Assert(args, check.DeepEquals, []interface{}{1})
+
+	dml.info.nullSafeEqual = true
+	builder.Reset()
+	args = dml.buildWhere(builder)
+	c.Assert(builder.String(), check.Equals, "`a1` <=> ? AND `id` <=> ?")
+	c.Assert(args, check.DeepEquals, []interface{}{nil, 1})
+
+	sql, args := dml.deleteSQL()
+	c.Assert(sql, check.Equals, "DELETE FROM `test`.`test` WHERE `a1` <=> ? AND `id` <=> ? LIMIT 1")
+	c.Assert(args, check.DeepEquals, []interface{}{nil, 1})
+}
+
+func (d *dmlSuite) TestUseIndexHint(c *check.C) {
+	dml := getDML(true, UpdateDMLType)
+	dml.info.useIndex = "PRIMARY"
+	dml.OldValues = map[string]interface{}{
+		"id": 1,
+		"a1": 1,
+	}
+	dml.Values = map[string]interface{}{
+		"id": 1,
+		"a1": 2,
+	}
+
+	sql, args := dml.updateSQL()
+	c.Assert(sql, check.Equals, "UPDATE `test`.`test` USE INDEX (`PRIMARY`) SET `a1` = ?,`id` = ? WHERE `id` = ? LIMIT 1")
+	c.Assert(args, check.DeepEquals, []interface{}{2, 1, 1})
+
+	dml = getDML(true, DeleteDMLType)
+	dml.info.useIndex = "PRIMARY"
+	dml.Values = map[string]interface{}{
+		"id": 1,
+		"a1": 1,
+	}
+
+	// not supported by the single table DELETE of MySQL
+	sql, args = dml.deleteSQL()
+	c.Assert(sql, check.Equals, "DELETE FROM `test`.`test` WHERE `id` = ? LIMIT 1")
+	c.Assert(args, check.DeepEquals, []interface{}{1})
+}
+
+func (d *dmlSuite) TestPrimaryKeyFirstInsert(c *check.C) {
+	dml := &DML{
+		Database: "test",
+		Table:    "test",
+		Tp:       InsertDMLType,
+		Values: map[string]interface{}{
+			"a":  1,
+			"id": 2,
+			"c":  3,
+			"b":  4,
+		},
+		info: &tableInfo{
+			columns:    []string{"a", "b", "c", "id"},
+			primaryKey: &indexInfo{"PRIMARY", []string{"id", "c"}},
+		},
+	}
+
+	sql, args := dml.insertSQL()
+	c.Assert(sql, check.Equals, "INSERT INTO `test`.`test`(`a`,`b`,`c`,`id`) VALUES(?,?,?,?)")
+	c.Assert(args, check.DeepEquals, []interface{}{1, 4, 3, 2})
+
+	dml.info.pkFirst = true
+	sql, args = dml.insertSQL()
+	c.Assert(sql, check.Equals, "INSERT INTO `test`.`test`(`id`,`c`,`a`,`b`) VALUES(?,?,?,?)")
+	c.Assert(args, check.DeepEquals, []interface{}{2, 3, 1, 4})
+
+	sql, args = dml.replaceSQL()
+	c.Assert(sql, check.Equals, "REPLACE INTO `test`.`test`(`id`,`c`,`a`,`b`) VALUES(?,?,?,?)")
+	c.Assert(args, check.DeepEquals, []interface{}{2, 3, 1, 4})
+}
+
+func (d *dmlSuite) TestBeforeImageComment(c *check.C) {
+	dml := getDML(true, UpdateDMLType)
+	dml.info.beforeImageComment = true
+	dml.OldValues = map[string]interface{}{
+		"id": 1,
+		"a1": 1,
+	}
+	dml.Values = map[string]interface{}{
+		"id": 1,
+		"a1": 2,
+	}
+
+	sql, args := dml.updateSQL()
+	c.Assert(sql, check.Equals, "UPDATE `test`.`test` SET `a1` = ?,`id` = ? WHERE `id` = ? LIMIT 1 /* before: `id`=1 */")
+	c.Assert(args, check.DeepEquals, []interface{}{2, 1, 1})
+
+	// the values can't end the comment or add placeholders
+	dml = getDML(true, UpdateDMLType)
+	dml.info.beforeImageComment = true
+	dml.OldValues = map[string]interface{}{
+		"id": "1 */; DROP TABLE t; /* ?",
+		"a1": 1,
+	}
+	dml.Values = map[string]interface{}{
+		"id": "2",
+		"a1": 2,
+	}
+
+	sql, args = dml.updateSQL()
+	comment := sql[strings.Index(sql, "/*"):]
+	c.Assert(comment, check.Equals, `/* before: `+"`id`"+`="1 *\/; DROP TABLE t; /* \x3f" */`)
+	c.Assert(strings.Count(comment, "*/"), check.Equals, 1)
+	c.Assert(strings.Count(sql, "?"), check.Equals, len(args))
+}
+
+func (d *dmlSuite) TestQuoteReservedNames(c *check.C) {
+	info := &tableInfo{
+		columns:    []string{"from", "my-col", "a`b"},
+		uniqueKeys: []indexInfo{{"PRIMARY", []string{"from"}}},
+	}
+	info.primaryKey = &info.uniqueKeys[0]
+	values := map[string]interface{}{"from": 1, "my-col": 2, "a`b": 3}
+
+	dmls := []*DML{
+		{Database: "order", Table: "select", Tp: InsertDMLType, Values: values, info: info},
+		{Database: "order", Table: "select", Tp: UpdateDMLType, Values: values, OldValues: values, info: info},
+		{Database: "order", Table: "select", Tp: DeleteDMLType, Values: values, info: info},
+	}
+
+	sql, _ := dmls[0].sql()
+	c.Assert(sql, check.Equals, "INSERT INTO `order`.`select`(`a``b`,`from`,`my-col`) VALUES(?,?,?)")
+
+	for _, dml := range dmls {
+		sql, _ := dml.sql()
+		_, err := parser.New().ParseOneStmt(sql, "", "")
+		c.Assert(err, check.IsNil, check.Commentf("sql: %s", sql))
+
+		sql, _ = dml.replaceSQL()
+		_, err = parser.New().ParseOneStmt(sql, "", "")
+		c.Assert(err, check.IsNil, check.Commentf("sql: %s", sql))
+	}
+}
+
 type getKeysSuite struct{}
 
 var _ = check.Suite(&getKeysSuite{})
@@ -192,6 +367,34 @@ func (s *SQLSuite) TestInsertSQL(c *check.C) {
 	c.Assert(args[1], check.Equals, "pc")
 }
 
+func (s *SQLSuite) TestReplaceSQL(c *check.C) {
+	dml := DML{
+		Tp:       InsertDMLType,
+		Database: "test",
+		Table:    "hello",
+		Values: map[string]interface{}{
+			"name": "pc",
+			"age":  42,
+		},
+		info: &tableInfo{
+			columns: []string{"name", "age"},
+		},
+	}
+	sql, args := dml.replaceSQL()
+	c.Assert(sql, check.Equals, "REPLACE INTO `test`.`hello`(`age`,`name`) VALUES(?,?)")
+	c.Assert(args, check.DeepEquals, []interface{}{42, "pc"})
+
+	// use on duplicate update for the table referenced by foreign keys
+	dml.info.referenced = true
+	sql, args = dml.replaceSQL()
+	c.Assert(sql, check.Equals, "INSERT INTO `test`.`hello`(`age`,`name`) VALUES(?,?) ON DUPLICATE KEY UPDATE `age`=VALUES(`age`),`name`=VALUES(`name`)")
+	c.Assert(args, check.DeepEquals, []interface{}{42, "pc"})
+
+	// insert is not affected
+	sql, _ = dml.sql()
+	c.Assert(sql, check.Equals, "INSERT INTO `test`.`hello`(`age`,`name`) VALUES(?,?)")
+}
+
 func (s *SQLSuite) TestDeleteSQL(c *check.C) {
 	dml := DML{
 		Tp:       DeleteDMLType,
@@ -214,6 +417,66 @@ func (s *SQLSuite) TestDeleteSQL(c *check.C) {
 	c.Assert(args[1], check.Equals, "pc")
 }
 
+func (s *SQLSuite) TestDeleteSQLByCompositePrimaryKey(c *check.C) {
+	dml := DML{
+		Tp:       DeleteDMLType,
+		Database: "test",
+		Table:    "hello",
+		Values: map[string]interface{}{
+			"tenant": 3,
+			"id":     10,
+			"name":   "pc",
+		},
+		info: &tableInfo{
+			columns:    []string{"tenant", "id", "name"},
+			primaryKey: &indexInfo{name: "PRIMARY", columns: []string{"tenant", "id"}},
+			uniqueKeys: []indexInfo{
+				{name: "PRIMARY", columns: []string{"tenant", "id"}},
+			},
+		},
+	}
+	sql, args := dml.sql()
+	c.Assert(sql, check.Equals, "DELETE FROM `test`.`hello` WHERE `tenant` = ? AND `id` = ? LIMIT 1")
+	c.Assert(args, check.DeepEquals, []interface{}{3, 10})
+}
+
+func (s *SQLSuite) TestHash(c *check.C) {
+	newDML := func() *DML {
+		return &DML{
+			Tp:       UpdateDMLType,
+			Database: "test",
+			Table:    "hello",
+			OldValues: map[string]interface{}{
+				"id":   1,
+				"name": []byte("pc"),
+			},
+			Values: map[string]interface{}{
+				"id":   1,
+				"name": []byte("pingcap"),
+			},
+		}
+	}
+
+	dml := newDML()
+	c.Assert(dml.Hash(), check.Equals, newDML().Hash())
+	c.Assert(dml.Hash(), check.HasLen, 64)
+
+	changes := []func(dml *DML){
+		func(dml *DML) { dml.Database = "test1" },
+		func(dml *DML) { dml.Table = "hello1" },
+		func(dml *DML) { dml.Tp = InsertDMLType },
+		func(dml *DML) { dml.Values["id"] = 2 },
+		func(dml *DML) { dml.Values["id"] = "1" },
+		func(dml *DML) { dml.OldValues["name"] = []byte("pingcap") },
+		func(dml *DML) { dml.Values["age"] = nil },
+	}
+	for i, change := range changes {
+		other := newDML()
+		change(other)
+		c.Assert(other.Hash(), check.Not(check.Equals), dml.Hash(), check.Commentf("change %d", i))
+	}
+}
+
 func (s *SQLSuite) TestUpdateSQL(c *check.C) {
 	dml := DML{
 		Tp:       UpdateDMLType,
@@ -238,6 +501,79 @@ func (s *SQLSuite) TestUpdateSQL(c *check.C) {
 	c.Assert(args[1], check.Equals, "pingcap")
 }
 
+func (s *SQLSuite) TestUpdateSQLPartialCompositeKeyChange(c *check.C) {
+	dml := DML{
+		Tp:       UpdateDMLType,
+		Database: "db",
+		Table:    "tbl",
+		OldValues: map[string]interface{}{
+			"tenant": 3,
+			"id":     10,
+			"name":   "pc",
+		},
+		Values: map[string]interface{}{
+			"tenant": 3,
+			"id":     11,
+			"name":   "pc",
+		},
+		info: &tableInfo{
+			columns:    []string{"tenant", "id", "name"},
+			primaryKey: &indexInfo{name: "PRIMARY", columns: []string{"tenant", "id"}},
+			uniqueKeys: []indexInfo{
+				{name: "PRIMARY", columns: []string{"tenant", "id"}},
+			},
+		},
+	}
+	c.Assert(dml.updateKey(), check.IsTrue)
+
+	// all the old key values in WHERE, and the new values in SET
+	sql, args := dml.sql()
+	c.Assert(sql, check.Equals, "UPDATE `db`.`tbl` SET `id` = ?,`name` = ?,`tenant` = ? WHERE `tenant` = ? AND `id` = ? LIMIT 1")
+	c.Assert(args, check.DeepEquals, []interface{}{11, "pc", 3, 3, 10})
+}
+
+func (s *SQLSuite) TestUniqueKeyOnlyTable(c *check.C) {
+	info := &tableInfo{
+		columns:    []string{"id", "email", "name"},
+		uniqueKeys: []indexInfo{{name: "uk_email", columns: []string{"email"}}},
+	}
+	newDML := func(tp DMLType) *DML {
+		dml := &DML{
+			Tp:       tp,
+			Database: "db",
+			Table:    "tbl",
+			Values: map[string]interface{}{
+				"id":    1,
+				"email": "pc@pingcap.com",
+				"name":  "pc",
+			},
+			info: info,
+		}
+		if tp == UpdateDMLType {
+			dml.OldValues = map[string]interface{}{
+				"id":    1,
+				"email": "old@pingcap.com",
+				"name":  "pc",
+			}
+		}
+		return dml
+	}
+
+	// replace and on duplicate key update conflict with the row by the unique key
+	sql, _ := newDML(InsertDMLType).replaceSQL()
+	c.Assert(sql, check.Equals, "REPLACE INTO `db`.`tbl`(`email`,`id`,`name`) VALUES(?,?,?)")
+	sql, _ = newDML(InsertDMLType).insertOnDuplicateSQL()
+	c.Assert(sql, check.Equals, "INSERT INTO `db`.`tbl`(`email`,`id`,`name`) VALUES(?,?,?) ON DUPLICATE KEY UPDATE `email`=VALUES(`email`),`id`=VALUES(`id`),`name`=VALUES(`name`)")
+
+	sql, args := newDML(UpdateDMLType).sql()
+	c.Assert(sql, check.Equals, "UPDATE `db`.`tbl` SET `email` = ?,`id` = ?,`name` = ? WHERE `email` = ? LIMIT 1")
+	c.Assert(args, check.DeepEquals, []interface{}{"pc@pingcap.com", 1, "pc", "old@pingcap.com"})
+
+	sql, args = newDML(DeleteDMLType).sql()
+	c.Assert(sql, check.Equals, "DELETE FROM `db`.`tbl` WHERE `email` = ? LIMIT 1")
+	c.Assert(args, check.DeepEquals, []interface{}{"pc@pingcap.com"})
+}
+
 func (s *SQLSuite) TestUpdateMarkSQL(c *check.C) {
 	db, mock, err := sqlmock.New()
 	c.Assert(err, check.IsNil)
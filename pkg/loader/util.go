@@ -38,6 +38,15 @@ var (
 )
 
 const (
+	createDDLMarkerTableSQL = `
+CREATE TABLE IF NOT EXISTS %s (
+	id bigint NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	commit_ts bigint NOT NULL,
+	db_name varchar(64) NOT NULL DEFAULT '',
+	table_name varchar(64) NOT NULL DEFAULT '',
+	ddl text NOT NULL,
+	applied_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);`
 	colsSQL = `
 SELECT column_name, extra FROM information_schema.columns
 WHERE table_schema = ? AND table_name = ?;`
@@ -46,6 +55,9 @@ SELECT non_unique, index_name, seq_in_index, column_name
 FROM information_schema.statistics
 WHERE table_schema = ? AND table_name = ?
 ORDER BY seq_in_index ASC;`
+	referencedSQL = `
+SELECT COUNT(*) FROM information_schema.key_column_usage
+WHERE referenced_table_schema = ? AND referenced_table_name = ?;`
 )
 
 type tableInfo struct {
@@ -53,6 +65,46 @@ type tableInfo struct {
 	primaryKey *indexInfo
 	// include primary key if have
 	uniqueKeys []indexInfo
+	// whether the table is referenced by any foreign key of other tables
+	referenced bool
+	// use the null-safe equal operator `<=>` in WHERE
+	nullSafeEqual bool
+	// the index hinted by USE INDEX for update, empty means no hint
+	useIndex string
+	// put the primary key columns first in the column list of insert and replace
+	pkFirst bool
+	// append a comment recording the before-image key values to update
+	beforeImageComment bool
+}
+
+// orderColumns moves the primary key columns to the front of names if pkFirst is set,
+// the order of other columns is kept.
+func (info *tableInfo) orderColumns(names []string) []string {
+	if !info.pkFirst || info.primaryKey == nil {
+		return names
+	}
+
+	isPK := make(map[string]struct{}, len(info.primaryKey.columns))
+	for _, col := range info.primaryKey.columns {
+		isPK[col] = struct{}{}
+	}
+
+	ordered := make([]string, 0, len(names))
+	for _, col := range info.primaryKey.columns {
+		for _, name := range names {
+			if name == col {
+				ordered = append(ordered, name)
+				break
+			}
+		}
+	}
+	for _, name := range names {
+		if _, ok := isPK[name]; !ok {
+			ordered = append(ordered, name)
+		}
+	}
+
+	return ordered
 }
 
 type indexInfo struct {
@@ -306,3 +358,27 @@ func getUniqKeys(db *gosql.DB, schema, table string) (uniqueKeys []indexInfo, er
 
 	return
 }
+
+// isReferencedByFK returns whether the table is a parent table of any foreign key.
+// https://dev.mysql.com/doc/mysql-infoschema-excerpt/5.7/en/key-column-usage-table.html
+func isReferencedByFK(db *gosql.DB, schema, table string) (bool, error) {
+	var count int
+	err := db.QueryRow(referencedSQL, schema, table).Scan(&count)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+
+	return count > 0, nil
+}
+
+func buildOnDuplicateList(names []string) string {
+	var b strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, "%s=VALUES(%s)", quoteName(name), quoteName(name))
+	}
+
+	return b.String()
+}
@@ -82,5 +82,7 @@ func (cs *UtilSuite) TestGetTableInfo(c *check.C) {
 		uniqueKeys: []indexInfo{{"PRIMARY", []string{"id"}},
 			{"dex1", []string{"a1"}},
 			{"dex2", []string{"a2", "a3"}},
-		}})
+		},
+	})
+	c.Assert(mock.ExpectationsWereMet(), check.IsNil)
 }
@@ -28,6 +28,8 @@ import (
 	"github.com/pingcap/tidb-binlog/pkg/util"
 	tddl "github.com/pingcap/tidb/ddl"
 	"github.com/pingcap/tidb/infoschema"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/parser/format"
 	tmysql "github.com/pingcap/tidb/parser/mysql"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
@@ -301,3 +303,25 @@ func QuoteName(name string) string {
 func escapeName(name string) string {
 	return strings.Replace(name, "`", "``", -1)
 }
+
+// Normalize returns the canonical form of the statements to compare them in tests,
+// with keywords in lower case, whitespaces collapsed and all names quoted by backticks.
+// The statements are only collapsed if they can't be parsed.
+func Normalize(sql string) string {
+	stmts, _, err := parser.New().Parse(sql, "", "")
+	if err != nil {
+		return strings.Join(strings.Fields(sql), " ")
+	}
+
+	flags := format.RestoreKeyWordLowercase | format.RestoreNameBackQuotes | format.RestoreStringSingleQuotes
+	normalized := make([]string, 0, len(stmts))
+	for _, stmt := range stmts {
+		var builder strings.Builder
+		if err := stmt.Restore(format.NewRestoreCtx(flags, &builder)); err != nil {
+			return strings.Join(strings.Fields(sql), " ")
+		}
+		normalized = append(normalized, builder.String())
+	}
+
+	return strings.Join(normalized, "; ")
+}
@@ -37,6 +37,22 @@ func (s *quoteSuite) TestQuoteSchema(c *C) {
 	c.Assert(QuoteSchema("wEi`rd", "Na`me"), Equals, "`wEi``rd`.`Na``me`")
 }
 
+func (s *quoteSuite) TestNormalize(c *C) {
+	tests := [][2]string{
+		{"REPLACE INTO `test`.`t1`(`a`,`b`) VALUES(?,?)", "replace into test.t1 (a, b)  values (?, ?)"},
+		{"DELETE FROM `test`.`t1` WHERE `a` = ? AND `b` IS NULL LIMIT 1", "delete\n\tfrom test.t1 where a=? and b is null limit 1"},
+		{"UPDATE t1 SET a = 'x'", "update `t1` set `a` = \"x\""},
+		{"DELETE FROM t1 WHERE a = 1; DELETE FROM t1 WHERE a = 2;", "delete from t1 where a = 1;delete from t1 where a = 2"},
+	}
+	for _, test := range tests {
+		c.Assert(Normalize(test[0]), Equals, Normalize(test[1]), Commentf("sql: %s", test[0]))
+	}
+
+	c.Assert(Normalize("SELECT a FROM t1"), Not(Equals), Normalize("SELECT b FROM t1"))
+	// only the whitespaces are collapsed for statements can't be parsed
+	c.Assert(Normalize("  not   a\nstatement "), Equals, "not a statement")
+}
+
 type parseCHAddrSuite struct{}
 
 var _ = Suite(&parseCHAddrSuite{})
@@ -47,10 +47,11 @@ func (d *datum) setInitInt64Value(step int64, min int64, max int64) {
 	}
 
 	d.step = step
-
-	if min != -1 {
-		d.minIntValue = min
-		d.intValue = min
+	d.minIntValue = min
+	d.intValue = min
+	// a negative step goes down from the max
+	if step < 0 {
+		d.intValue = max
 	}
 
 	if min < max {
@@ -67,7 +68,8 @@ func (d *datum) uniqInt64() int64 {
 
 	data := d.intValue
 	if d.useRange {
-		if d.intValue+d.step > d.maxIntValue {
+		next := d.intValue + d.step
+		if next > d.maxIntValue || next < d.minIntValue {
 			return data
 		}
 	}
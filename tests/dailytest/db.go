@@ -21,6 +21,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
 	"github.com/pingcap/tidb-binlog/tests/util"
@@ -215,11 +216,27 @@ func genRowData(table *table) (string, error) {
 	return sql, nil
 }
 
+// randSetData picks an element of the set of the column, the values repeat even if the column is unique.
+func randSetData(column *column) string {
+	value := column.set[randInt(0, len(column.set)-1)]
+	switch column.tp.Tp {
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong,
+		mysql.TypeFloat, mysql.TypeDouble, mysql.TypeNewDecimal:
+		return value
+	}
+
+	return "'" + value + "'"
+}
+
 func genColumnData(table *table, column *column) (string, error) {
 	tp := column.tp
 	_, isUnique := table.uniqIndices[column.name]
 	isUnsigned := mysql.HasUnsignedFlag(tp.Flag)
 
+	if column.random && len(column.set) > 0 {
+		return randSetData(column), nil
+	}
+
 	switch tp.Tp {
 	case mysql.TypeTiny:
 		var data int64
@@ -271,7 +288,10 @@ func genColumnData(table *table, column *column) (string, error) {
 		return strconv.FormatInt(data, 10), nil
 	case mysql.TypeVarchar, mysql.TypeString, mysql.TypeTinyBlob, mysql.TypeBlob, mysql.TypeMediumBlob, mysql.TypeLongBlob:
 		data := []byte{'\''}
-		if isUnique {
+		if column.uuid {
+			// random uuids are unique too
+			data = append(data, []byte(uuid.New().String())...)
+		} else if isUnique {
 			data = append(data, []byte(column.data.uniqString(tp.Flen))...)
 		} else {
 			data = append(data, []byte(randString(randInt(1, tp.Flen)))...)
@@ -128,9 +128,8 @@ func doDDLProcess(table *table, db *sql.DB) {
 	index := randInt(2, len(table.columns)-1)
 	col := table.columns[index]
 
-	_, ok1 := table.indices[col.name]
-	_, ok2 := table.uniqIndices[col.name]
-	if !ok1 && !ok2 {
+	_, ok := table.indices[col.name]
+	if !ok && !table.isUniqueIndexColumn(col.name) {
 		newCols := make([]*column, 0, len(table.columns)-1)
 		newCols = append(newCols, table.columns[:index]...)
 		newCols = append(newCols, table.columns[index+1:]...)
@@ -22,6 +22,7 @@ import (
 	"github.com/pingcap/log"
 	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/mysql"
 	"github.com/pingcap/tidb/types"
 
 	// import parser_drive to avoid panic
@@ -38,6 +39,8 @@ type column struct {
 	max     string
 	step    int64
 	set     []string
+	random  bool
+	uuid    bool
 
 	table *table
 }
@@ -47,8 +50,8 @@ func (col *column) String() string {
 		return "<nil>"
 	}
 
-	return fmt.Sprintf("[column]idx: %d, name: %s, tp: %v, min: %s, max: %s, step: %d, set: %v\n",
-		col.idx, col.name, col.tp, col.min, col.max, col.step, col.set)
+	return fmt.Sprintf("[column]idx: %d, name: %s, tp: %v, min: %s, max: %s, step: %d, set: %v, random: %v, uuid: %v\n",
+		col.idx, col.name, col.tp, col.min, col.max, col.step, col.set, col.random, col.uuid)
 }
 
 func (col *column) parseRule(kvs []string) {
@@ -66,23 +69,82 @@ func (col *column) parseRule(kvs []string) {
 			col.min = strings.TrimSpace(fields[0])
 			col.max = strings.TrimSpace(fields[1])
 		}
+		if err := col.checkIntRange(); err != nil {
+			log.S().Fatal(err)
+		}
 	} else if key == "step" {
 		var err error
 		col.step, err = strconv.ParseInt(value, 10, 64)
 		if err != nil {
 			log.S().Fatal(err)
 		}
+		if col.step == 0 {
+			log.S().Fatalf("step of column %s must not be 0", col.name)
+		}
 	} else if key == "set" {
 		fields := strings.Split(value, ",")
 		for _, field := range fields {
 			col.set = append(col.set, strings.TrimSpace(field))
 		}
+	} else if key == "random" {
+		var err error
+		col.random, err = strconv.ParseBool(value)
+		if err != nil {
+			log.S().Fatal(err)
+		}
+	} else if key == "uuid" {
+		var err error
+		col.uuid, err = strconv.ParseBool(value)
+		if err != nil {
+			log.S().Fatal(err)
+		}
+	}
+}
+
+func (col *column) isInt() bool {
+	if col.tp == nil {
+		return false
+	}
+
+	switch col.tp.Tp {
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong:
+		return true
+	}
+	return false
+}
+
+// checkIntRange makes sure the range of an integer column is made of integers,
+// negative ones included, and min isn't greater than max.
+func (col *column) checkIntRange() error {
+	if !col.isInt() {
+		return nil
+	}
+
+	var min, max int64
+	var err error
+	if len(col.min) > 0 {
+		if min, err = strconv.ParseInt(col.min, 10, 64); err != nil {
+			return errors.Annotatef(err, "min of column %s", col.name)
+		}
+	}
+	if len(col.max) > 0 {
+		if max, err = strconv.ParseInt(col.max, 10, 64); err != nil {
+			return errors.Annotatef(err, "max of column %s", col.name)
+		}
+		if min > max {
+			return errors.Errorf("min %d is greater than max %d of column %s", min, max, col.name)
+		}
 	}
+
+	return nil
 }
 
 // parse the data rules.
 // rules like `a int unique comment '[[range=1,10;step=1]]'`,
-// then we will get value from 1,2...10
+// then we will get value from 1,2...10.
+// `a varchar(36) comment '[[uuid=true]]'` gets random uuids,
+// `a varchar(10) comment '[[set=x,y,z;random=true]]'` gets one of x, y and z picked randomly for every row,
+// `a int unique comment '[[range=-10,10;step=-1]]'` gets value from 10,9...-10.
 func (col *column) parseColumnComment() {
 	comment := strings.TrimSpace(col.comment)
 	start := strings.Index(comment, "[[")
@@ -112,18 +174,35 @@ func (col *column) parseColumnOptions(ops []*ast.ColumnOption) {
 	for _, op := range ops {
 		switch op.Tp {
 		case ast.ColumnOptionPrimaryKey, ast.ColumnOptionAutoIncrement, ast.ColumnOptionUniqKey:
-			col.table.uniqIndices[col.name] = col
+			col.table.uniqueIndices = append(col.table.uniqueIndices, &uniqueIndex{columns: []*column{col}})
 		case ast.ColumnOptionComment:
 			col.comment = op.Expr.(ast.ValueExpr).GetDatumString()
 		}
 	}
 }
 
+// uniqueIndex is a primary key or unique key, with the columns in the order of the index.
+type uniqueIndex struct {
+	columns []*column
+}
+
+func (idx *uniqueIndex) String() string {
+	names := make([]string, 0, len(idx.columns))
+	for _, col := range idx.columns {
+		names = append(names, col.name)
+	}
+
+	return fmt.Sprintf("(%s)", strings.Join(names, ","))
+}
+
 type table struct {
-	name         string
-	columns      []*column
-	columnList   string
-	indices      map[string]*column
+	name          string
+	columns       []*column
+	columnList    string
+	indices       map[string]*column
+	uniqueIndices []*uniqueIndex
+	// uniqIndices are the columns generating unique values,
+	// which makes the rows unique for all the unique indices.
 	uniqIndices  map[string]*column
 	unsignedCols map[string]*column
 }
@@ -154,8 +233,8 @@ func (t *table) String() string {
 	}
 
 	ret += fmt.Sprintf("[table]unique indices:\n")
-	for k, v := range t.uniqIndices {
-		ret += fmt.Sprintf("key->%s, value->%v", k, v)
+	for _, idx := range t.uniqueIndices {
+		ret += fmt.Sprintf("%v\n", idx)
 	}
 
 	return ret
@@ -182,10 +261,11 @@ func (t *table) parseTableConstraint(cons *ast.Constraint) {
 	switch cons.Tp {
 	case ast.ConstraintPrimaryKey, ast.ConstraintUniq,
 		ast.ConstraintUniqKey, ast.ConstraintUniqIndex:
+		idx := &uniqueIndex{columns: make([]*column, 0, len(cons.Keys))}
 		for _, indexCol := range cons.Keys {
-			name := indexCol.Column.Name.L
-			t.uniqIndices[name] = t.findCol(t.columns, name)
+			idx.columns = append(idx.columns, t.findCol(t.columns, indexCol.Column.Name.L))
 		}
+		t.uniqueIndices = append(t.uniqueIndices, idx)
 	case ast.ConstraintIndex, ast.ConstraintKey:
 		for _, indexCol := range cons.Keys {
 			name := indexCol.Column.Name.L
@@ -194,6 +274,29 @@ func (t *table) parseTableConstraint(cons *ast.Constraint) {
 	}
 }
 
+// isUniqueIndexColumn returns whether the column is in any primary key or unique key.
+func (t *table) isUniqueIndexColumn(name string) bool {
+	for _, idx := range t.uniqueIndices {
+		if t.findCol(idx.columns, name) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// buildUniqIndices picks the last column of every unique index to generate unique values,
+// so the tuples are unique while the other columns of a composite index may repeat.
+func (t *table) buildUniqIndices() {
+	t.uniqIndices = make(map[string]*column)
+	for _, idx := range t.uniqueIndices {
+		if len(idx.columns) == 0 {
+			continue
+		}
+		col := idx.columns[len(idx.columns)-1]
+		t.uniqIndices[col.name] = col
+	}
+}
+
 func (t *table) buildColumnList() {
 	columns := make([]string, 0, len(t.columns))
 	for _, column := range t.columns {
@@ -216,11 +319,163 @@ func parseTable(t *table, stmt *ast.CreateTableStmt) error {
 		t.parseTableConstraint(cons)
 	}
 
+	t.buildUniqIndices()
+	t.buildColumnList()
+
+	return nil
+}
+
+func (t *table) columnIndex(name string) int {
+	for i, col := range t.columns {
+		if col.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// insertColumn puts the column at the position, at index if no position is specified.
+func (t *table) insertColumn(col *column, pos *ast.ColumnPosition, index int) error {
+	if pos != nil {
+		switch pos.Tp {
+		case ast.ColumnPositionFirst:
+			index = 0
+		case ast.ColumnPositionAfter:
+			index = t.columnIndex(pos.RelativeColumn.Name.L)
+			if index < 0 {
+				return errors.NotFoundf("column %s", pos.RelativeColumn.Name.O)
+			}
+			index++
+		}
+	}
+
+	t.columns = append(t.columns, nil)
+	copy(t.columns[index+1:], t.columns[index:])
+	t.columns[index] = col
+	return nil
+}
+
+func (t *table) dropColumn(name string) (*column, error) {
+	index := t.columnIndex(name)
+	if index < 0 {
+		return nil, errors.NotFoundf("column %s", name)
+	}
+
+	col := t.columns[index]
+	t.columns = append(t.columns[:index], t.columns[index+1:]...)
+	delete(t.indices, name)
+	delete(t.unsignedCols, name)
+
+	// like MySQL, the column is removed from the unique indices, and the empty ones are dropped
+	indices := t.uniqueIndices[:0]
+	for _, idx := range t.uniqueIndices {
+		columns := idx.columns[:0]
+		for _, c := range idx.columns {
+			if c != col {
+				columns = append(columns, c)
+			}
+		}
+		idx.columns = columns
+		if len(idx.columns) > 0 {
+			indices = append(indices, idx)
+		}
+	}
+	t.uniqueIndices = indices
+	return col, nil
+}
+
+// newColumn parses the definition like creating the table, but keeps the column out of t.columns.
+func (t *table) newColumn(cd *ast.ColumnDef) *column {
+	columns := t.columns
+	col := &column{table: t, step: defaultStep, data: newDatum()}
+	col.parseColumn(cd)
+	t.columns = columns
+	return col
+}
+
+func (t *table) alterTableSpec(spec *ast.AlterTableSpec) error {
+	switch spec.Tp {
+	case ast.AlterTableAddColumns:
+		for _, cd := range spec.NewColumns {
+			if err := t.insertColumn(t.newColumn(cd), spec.Position, len(t.columns)); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	case ast.AlterTableDropColumn:
+		_, err := t.dropColumn(spec.OldColumnName.Name.L)
+		return errors.Trace(err)
+	case ast.AlterTableModifyColumn, ast.AlterTableChangeColumn:
+		oldName := spec.NewColumns[0].Name.Name.L
+		if spec.Tp == ast.AlterTableChangeColumn {
+			oldName = spec.OldColumnName.Name.L
+		}
+
+		index := t.columnIndex(oldName)
+		if index < 0 {
+			return errors.NotFoundf("column %s", oldName)
+		}
+		old := t.columns[index]
+		t.columns = append(t.columns[:index], t.columns[index+1:]...)
+		col := t.newColumn(spec.NewColumns[0])
+		// keep the generated data so the unique values go on from where they were
+		col.data = old.data
+		if _, ok := t.indices[oldName]; ok {
+			delete(t.indices, oldName)
+			t.indices[col.name] = col
+		}
+		if _, ok := t.unsignedCols[oldName]; ok {
+			delete(t.unsignedCols, oldName)
+			t.unsignedCols[col.name] = col
+		}
+		for _, idx := range t.uniqueIndices {
+			for i := range idx.columns {
+				if idx.columns[i] == old {
+					idx.columns[i] = col
+				}
+			}
+		}
+
+		// stay where the column was if no position is specified
+		return errors.Trace(t.insertColumn(col, spec.Position, index))
+	default:
+		return errors.Errorf("unsupported alter table spec - %v", spec.Tp)
+	}
+
+	return nil
+}
+
+// parseAlterTable applies the adding, dropping and modifying columns to the table.
+func parseAlterTable(t *table, stmt *ast.AlterTableStmt) error {
+	for _, spec := range stmt.Specs {
+		if err := t.alterTableSpec(spec); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
+	for i, col := range t.columns {
+		col.idx = i + 1
+	}
+	t.buildUniqIndices()
 	t.buildColumnList()
 
 	return nil
 }
 
+// parseAlterTableSQL applies the ALTER TABLE statement to the table parsed before.
+func parseAlterTableSQL(table *table, sql string) error {
+	stmt, err := parser.New().ParseOneStmt(sql, "", "")
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	node, ok := stmt.(*ast.AlterTableStmt)
+	if !ok {
+		return errors.Errorf("invalid statement - %v", stmt.Text())
+	}
+
+	return errors.Trace(parseAlterTable(table, node))
+}
+
 func parseTableSQL(table *table, sql string) error {
 	stmt, err := parser.New().ParseOneStmt(sql, "", "")
 	if err != nil {
@@ -230,6 +485,8 @@ func parseTableSQL(table *table, sql string) error {
 	switch node := stmt.(type) {
 	case *ast.CreateTableStmt:
 		err = parseTable(table, node)
+	case *ast.AlterTableStmt:
+		err = parseAlterTable(table, node)
 	default:
 		err = errors.Errorf("invalid statement - %v", stmt.Text())
 	}
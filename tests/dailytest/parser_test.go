@@ -0,0 +1,182 @@
+// Copyright 2021 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dailytest
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pingcap/check"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type parserSuite struct{}
+
+var _ = check.Suite(&parserSuite{})
+
+func (s *parserSuite) TestParseAlterTableSQL(c *check.C) {
+	t := newTable()
+	err := parseTableSQL(t, "create table t(id int primary key, a int, b varchar(10), index idx_b(b))")
+	c.Assert(err, check.IsNil)
+	c.Assert(t.columnList, check.Equals, "id,a,b")
+
+	err = parseAlterTableSQL(t, "alter table t add column c int unique after id")
+	c.Assert(err, check.IsNil)
+	c.Assert(t.columnList, check.Equals, "id,c,a,b")
+	c.Assert(t.uniqIndices, check.HasKey, "c")
+	c.Assert(t.columns[1].idx, check.Equals, 2)
+
+	err = parseAlterTableSQL(t, "alter table t drop column b")
+	c.Assert(err, check.IsNil)
+	c.Assert(t.columnList, check.Equals, "id,c,a")
+	c.Assert(t.indices, check.Not(check.HasKey), "b")
+
+	err = parseAlterTableSQL(t, "alter table t modify column a bigint first")
+	c.Assert(err, check.IsNil)
+	c.Assert(t.columnList, check.Equals, "a,id,c")
+
+	err = parseAlterTableSQL(t, "alter table t change column c d int")
+	c.Assert(err, check.IsNil)
+	c.Assert(t.columnList, check.Equals, "a,id,d")
+	c.Assert(t.uniqIndices, check.HasKey, "d")
+	c.Assert(t.uniqIndices, check.Not(check.HasKey), "c")
+
+	err = parseAlterTableSQL(t, "alter table t drop column x")
+	c.Assert(err, check.NotNil)
+	err = parseAlterTableSQL(t, "create table t2(id int)")
+	c.Assert(err, check.NotNil)
+}
+
+func (s *parserSuite) TestParseUUIDRule(c *check.C) {
+	t := newTable()
+	err := parseTableSQL(t, "create table t(id int primary key, a varchar(36) comment '[[uuid=true]]', b varchar(36))")
+	c.Assert(err, check.IsNil)
+	c.Assert(t.columns[1].uuid, check.IsTrue)
+	c.Assert(t.columns[2].uuid, check.IsFalse)
+
+	seen := make(map[string]struct{})
+	for i := 0; i < 10; i++ {
+		data, err := genColumnData(t, t.columns[1])
+		c.Assert(err, check.IsNil)
+		c.Assert(strings.HasPrefix(data, "'") && strings.HasSuffix(data, "'"), check.IsTrue)
+
+		id, err := uuid.Parse(strings.Trim(data, "'"))
+		c.Assert(err, check.IsNil)
+		c.Assert(id.Variant(), check.Equals, uuid.RFC4122)
+		c.Assert(seen, check.Not(check.HasKey), id.String())
+		seen[id.String()] = struct{}{}
+	}
+}
+
+func (s *parserSuite) TestCompositeUniqueIndex(c *check.C) {
+	t := newTable()
+	err := parseTableSQL(t, "create table t(id int primary key, a tinyint comment '[[range=1,3]]', b int, unique key uk(a, b))")
+	c.Assert(err, check.IsNil)
+	c.Assert(t.uniqueIndices, check.HasLen, 2)
+	c.Assert(t.uniqueIndices[1].String(), check.Equals, "(a,b)")
+	c.Assert(t.isUniqueIndexColumn("a"), check.IsTrue)
+	c.Assert(t.uniqIndices, check.Not(check.HasKey), "a")
+	c.Assert(t.uniqIndices, check.HasKey, "b")
+
+	pairs := make(map[string]struct{})
+	as := make(map[string]struct{})
+	for i := 0; i < 50; i++ {
+		a, err := genColumnData(t, t.columns[1])
+		c.Assert(err, check.IsNil)
+		b, err := genColumnData(t, t.columns[2])
+		c.Assert(err, check.IsNil)
+
+		pair := a + "," + b
+		c.Assert(pairs, check.Not(check.HasKey), pair)
+		pairs[pair] = struct{}{}
+		as[a] = struct{}{}
+	}
+	// the leading column repeats within its range
+	c.Assert(len(as) <= 3, check.IsTrue)
+
+	err = parseAlterTableSQL(t, "alter table t drop column b")
+	c.Assert(err, check.IsNil)
+	c.Assert(t.uniqueIndices[1].String(), check.Equals, "(a)")
+	c.Assert(t.uniqIndices, check.HasKey, "a")
+}
+
+func (s *parserSuite) TestParseRandomSetRule(c *check.C) {
+	t := newTable()
+	err := parseTableSQL(t, "create table t(id int primary key, a varchar(10) comment '[[set=x,y,z;random=true]]', b int comment '[[set=1,2,3;random=true]]', c int comment '[[set=1,2,3]]')")
+	c.Assert(err, check.IsNil)
+	c.Assert(t.columns[1].random, check.IsTrue)
+	c.Assert(t.columns[1].set, check.DeepEquals, []string{"x", "y", "z"})
+	c.Assert(t.columns[2].random, check.IsTrue)
+	c.Assert(t.columns[3].random, check.IsFalse)
+
+	as := make(map[string]struct{})
+	for i := 0; i < 100; i++ {
+		a, err := genColumnData(t, t.columns[1])
+		c.Assert(err, check.IsNil)
+		c.Assert(a, check.Matches, "'[xyz]'")
+		as[a] = struct{}{}
+
+		b, err := genColumnData(t, t.columns[2])
+		c.Assert(err, check.IsNil)
+		c.Assert(b, check.Matches, "[123]")
+	}
+	// picked randomly rather than one after another
+	c.Assert(len(as) > 1, check.IsTrue)
+}
+
+func (s *parserSuite) TestParseNegativeRange(c *check.C) {
+	t := newTable()
+	err := parseTableSQL(t, "create table t(id int primary key comment '[[range=-50,50;step=-1]]', a int unique comment '[[range=-1,1]]', b int comment '[[range=-100,-1]]')")
+	c.Assert(err, check.IsNil)
+
+	id := t.columns[0]
+	c.Assert(id.min, check.Equals, "-50")
+	c.Assert(id.max, check.Equals, "50")
+	c.Assert(id.step, check.Equals, int64(-1))
+	c.Assert(id.checkIntRange(), check.IsNil)
+
+	// descending from the max, and stays at the min
+	var ids []string
+	for i := 0; i < 102; i++ {
+		data, err := genColumnData(t, id)
+		c.Assert(err, check.IsNil)
+		ids = append(ids, data)
+	}
+	c.Assert(ids[:3], check.DeepEquals, []string{"50", "49", "48"})
+	c.Assert(ids[100:], check.DeepEquals, []string{"-50", "-50"})
+
+	var as []string
+	for i := 0; i < 3; i++ {
+		data, err := genColumnData(t, t.columns[1])
+		c.Assert(err, check.IsNil)
+		as = append(as, data)
+	}
+	c.Assert(as, check.DeepEquals, []string{"-1", "0", "1"})
+
+	for i := 0; i < 50; i++ {
+		data, err := genColumnData(t, t.columns[2])
+		c.Assert(err, check.IsNil)
+		b, err := strconv.ParseInt(data, 10, 64)
+		c.Assert(err, check.IsNil)
+		c.Assert(b >= -100 && b <= -1, check.IsTrue, check.Commentf("value: %d", b))
+	}
+
+	col := &column{name: "c", tp: t.columns[2].tp, min: "10", max: "-10"}
+	c.Assert(col.checkIntRange(), check.ErrorMatches, "min 10 is greater than max -10 of column c")
+	col = &column{name: "c", tp: t.columns[2].tp, min: "-1x"}
+	c.Assert(col.checkIntRange(), check.ErrorMatches, "min of column c.*")
+}